@@ -0,0 +1,186 @@
+// Package alert notifies chats when exchange rates cross thresholds.
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/chat"
+	"github.com/koorgoo/vtb24/locale"
+	"github.com/koorgoo/vtb24/money"
+)
+
+var (
+	ErrNotFound = errors.New("alert: subscription not found")
+	ErrSyntax   = errors.New("alert: invalid subscription")
+	ErrCurrency = errors.New("alert: unknown currency pair")
+	ErrGroup    = errors.New("alert: unknown group")
+	ErrLimit    = errors.New("alert: too many subscriptions")
+)
+
+type Side string
+
+const (
+	Buy  Side = "buy"
+	Sell Side = "sell"
+)
+
+type Op string
+
+const (
+	Less         Op = "<"
+	LessEqual    Op = "<="
+	Greater      Op = ">"
+	GreaterEqual Op = ">="
+)
+
+func (op Op) holds(x, y money.Decimal) bool {
+	switch c := x.Cmp(y); op {
+	case Less:
+		return c < 0
+	case LessEqual:
+		return c <= 0
+	case Greater:
+		return c > 0
+	case GreaterEqual:
+		return c >= 0
+	default:
+		return false
+	}
+}
+
+// Subscription is a condition on a rate of an exchange.
+type Subscription struct {
+	ID     int64         `json:"id"`
+	ChatID int64         `json:"chat_id"`
+	Src    string        `json:"src"`
+	Dst    string        `json:"dst"`
+	Group  string        `json:"group"`
+	Side   Side          `json:"side"`
+	Op     Op            `json:"op"`
+	Value  money.Decimal `json:"value"`
+
+	// Fired is true while the condition holds. A subscription fires again
+	// only after the condition stops holding.
+	Fired bool `json:"fired"`
+}
+
+func (s *Subscription) String() string { return s.Format(locale.New(locale.Default)) }
+
+// Format returns s in a language of p.
+func (s *Subscription) Format(p *locale.Printer) string {
+	return fmt.Sprintf("%s › %s (%s): %s %s %s",
+		s.Src, s.Dst, p.Group(s.Group), p.Sprintf(sideText[s.Side]), s.Op, chat.FormatDecimal(s.Value))
+}
+
+var sideText = map[Side]string{
+	Buy:  locale.MsgBuy,
+	Sell: locale.MsgSell,
+}
+
+// match returns e or inverted e when it quotes a pair and a group of s.
+func (s *Subscription) match(e bank.Ex) (bank.Ex, bool) {
+	switch {
+	case e.Group() != s.Group:
+		return nil, false
+	case e.Src() == s.Src && e.Dst() == s.Dst:
+		return e, true
+	case e.Src() == s.Dst && e.Dst() == s.Src:
+		return bank.Invert(e), true
+	}
+	return nil, false
+}
+
+// Alert is sent when a subscription fires.
+type Alert struct {
+	Subscription Subscription
+	Rate         money.Decimal
+}
+
+func (a *Alert) String() string { return a.Format(locale.New(locale.Default)) }
+
+// Format returns a in a language of p.
+func (a *Alert) Format(p *locale.Printer) string {
+	s := &a.Subscription
+	return fmt.Sprintf("%s › %s (%s): %s *%s* %s %s",
+		s.Src, s.Dst, p.Group(s.Group), p.Sprintf(sideText[s.Side]),
+		chat.FormatDecimal(a.Rate), s.Op, chat.FormatDecimal(s.Value))
+}
+
+// Rate returns a base rate of e for side, i.e. the rate for the lowest
+// threshold.
+func Rate(e bank.Ex, side Side) (money.Decimal, bool) {
+	buy, sell := e.Tiers()
+	switch {
+	case side == Buy && len(buy) > 0:
+		return buy[0].Rate, true
+	case side == Sell && len(sell) > 0:
+		return sell[0].Rate, true
+	default:
+		return money.Decimal{}, false
+	}
+}
+
+// Parse parses a subscription from args like "USD sell < 60" or
+// "EUR/RUB cash buy >= 70". Group defaults to api.GroupTele. Currencies
+// must be known to chat.Currencies and the group to api.
+func Parse(args []string) (s Subscription, err error) {
+	if len(args) != 4 && len(args) != 5 {
+		err = ErrSyntax
+		return
+	}
+	if strings.Count(args[0], "/") > 1 {
+		err = ErrSyntax
+		return
+	}
+	if s.Src, s.Dst, err = api.ParsePair(args[0]); err != nil || !isCurrency(s.Src) || !isCurrency(s.Dst) {
+		s, err = Subscription{}, ErrCurrency
+		return
+	}
+	args = args[1:]
+
+	s.Group = api.GroupTele
+	if len(args) == 4 {
+		s.Group, args = strings.ToLower(args[0]), args[1:]
+	}
+	if !api.IsGroup(s.Group) {
+		s, err = Subscription{}, ErrGroup
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "buy", "покупка":
+		s.Side = Buy
+	case "sell", "продажа":
+		s.Side = Sell
+	default:
+		err = ErrSyntax
+		return
+	}
+
+	switch op := Op(args[1]); op {
+	case Less, LessEqual, Greater, GreaterEqual:
+		s.Op = op
+	default:
+		err = ErrSyntax
+		return
+	}
+
+	v := strings.Replace(args[2], ",", ".", 1)
+	if s.Value, err = money.Parse(v); err != nil || s.Value.Sign() <= 0 {
+		err = ErrSyntax
+	}
+	return
+}
+
+func isCurrency(code string) bool {
+	for _, c := range chat.Currencies {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package alert
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/money"
+)
+
+var ParseTests = []struct {
+	Args string
+	Sub  Subscription
+	OK   bool
+}{
+	{"USD sell < 60", Subscription{Src: "USD", Dst: "RUB", Group: "tele", Side: Sell, Op: Less, Value: money.New(60, 0)}, true},
+	{"eur/usd cash buy >= 1,15", Subscription{Src: "EUR", Dst: "USD", Group: "cash", Side: Buy, Op: GreaterEqual, Value: money.New(115, 2)}, true},
+	{"USD продажа > 60", Subscription{Src: "USD", Dst: "RUB", Group: "tele", Side: Sell, Op: Greater, Value: money.New(60, 0)}, true},
+	{"USD sell = 60", Subscription{}, false},
+	{"USD sell < x", Subscription{}, false},
+	{"USD side < 60", Subscription{}, false},
+	{"A/B/C sell < 60", Subscription{}, false},
+	{"USD", Subscription{}, false},
+	{"usd/rbu cash sell > 100", Subscription{}, false},
+	{"XYZ sell > 100", Subscription{}, false},
+	{"usd cahs sell > 100", Subscription{}, false},
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range ParseTests {
+		t.Run(tt.Args, func(t *testing.T) {
+			s, err := Parse(strings.Fields(tt.Args))
+			if ok := (err == nil); ok != tt.OK {
+				t.Fatalf("error: want %v, got %v: %v", tt.OK, ok, err)
+			}
+			if !equal(s, tt.Sub) && tt.OK {
+				t.Errorf("want %+v, got %+v", tt.Sub, s)
+			}
+		})
+	}
+}
+
+func equal(a, b Subscription) bool {
+	if a.Value.Cmp(b.Value) != 0 {
+		return false
+	}
+	a.Value, b.Value = money.Decimal{}, money.Decimal{}
+	return a == b
+}
+
+func makeEx(sell string) []bank.Ex {
+	return bank.ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("55"), Sell: api.MustValue(sell)},
+		},
+	}, time.Time{})
+}
+
+func TestStore_Check(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "alerts.json")
+	s, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := s.Add(Subscription{ChatID: 1, Src: "USD", Dst: "RUB", Group: "tele", Side: Sell, Op: Less, Value: money.New(60, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		Sell   string
+		Alerts int
+	}{
+		{"61", 0},
+		{"59", 1},
+		{"58", 0}, // still below, must not fire again
+		{"62", 0},
+		{"59.5", 1},
+	}
+
+	for i, step := range steps {
+		alerts, err := s.Check(makeEx(step.Sell))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(alerts) != step.Alerts {
+			t.Errorf("%d: want %d alerts, got %d", i, step.Alerts, len(alerts))
+		}
+	}
+
+	// Fired state and subscriptions survive reopening.
+	s2, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := s2.List(1); len(v) != 1 || !v[0].Fired {
+		t.Fatalf("want 1 fired subscription, got %+v", v)
+	}
+	if err := s2.Delete(2, sub.ID); err != ErrNotFound {
+		t.Errorf("want %v, got %v", ErrNotFound, err)
+	}
+	if err := s2.Delete(1, sub.ID); err != nil {
+		t.Fatal(err)
+	}
+	if v := s2.List(1); len(v) != 0 {
+		t.Errorf("want no subscriptions, got %+v", v)
+	}
+}
+
+func TestStore_Check_inverted(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "alerts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The bank sells RUB for USD at 1/55 when it buys USD at 55.
+	_, err = s.Add(Subscription{ChatID: 1, Src: "RUB", Dst: "USD", Group: "tele", Side: Sell, Op: Greater, Value: money.New(18, 3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts, err := s.Check(makeEx("60"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].Rate.Cmp(money.New(1, 0).Div(money.New(55, 0))) != 0 {
+		t.Errorf("want an alert at 1/55, got %+v", alerts)
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	tests := []struct {
+		Args string
+		Err  error
+	}{
+		{"usd/rbu cash sell > 100", ErrCurrency},
+		{"usd cahs sell > 100", ErrGroup},
+		{"usd cash sell = 100", ErrSyntax},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(strings.Fields(tt.Args)); err != tt.Err {
+			t.Errorf("%s: want %v, got %v", tt.Args, tt.Err, err)
+		}
+	}
+}
+
+func TestStore_Add_limit(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "alerts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := Subscription{ChatID: 1, Src: "USD", Dst: "RUB", Group: "tele", Side: Sell, Op: Less, Value: money.New(60, 0)}
+	for i := 0; i < MaxSubscriptions; i++ {
+		if _, err := s.Add(sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Add(sub); err != ErrLimit {
+		t.Errorf("want %v, got %v", ErrLimit, err)
+	}
+	// Other chats are not limited.
+	sub.ChatID = 2
+	if _, err := s.Add(sub); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStore_saveFailed(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(filepath.Join(dir, "alerts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := Subscription{ChatID: 1, Src: "USD", Dst: "RUB", Group: "tele", Side: Sell, Op: Less, Value: money.New(60, 0)}
+	for i := 0; i < 2; i++ {
+		if _, err = s.Add(sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Saving fails when the directory is gone.
+	if err = os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = s.Add(sub); err == nil {
+		t.Error("add: want error")
+	}
+	if err = s.Delete(1, 1); err == nil {
+		t.Error("delete: want error")
+	}
+	v := s.List(1)
+	if len(v) != 2 || v[0].ID != 1 || v[1].ID != 2 {
+		t.Errorf("want subscriptions 1 and 2, got %+v", v)
+	}
+}
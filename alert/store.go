@@ -0,0 +1,139 @@
+package alert
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/jsonfile"
+)
+
+// MaxSubscriptions limits subscriptions of a chat.
+const MaxSubscriptions = 20
+
+// Store keeps subscriptions in a JSON file.
+type Store struct {
+	filename string
+
+	mu   sync.Mutex
+	subs []Subscription
+	seq  int64
+}
+
+// Open loads subscriptions from filename, which may not exist yet.
+func Open(filename string) (*Store, error) {
+	s := &Store{filename: filename}
+	if err := jsonfile.Load(filename, &s.subs); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("alert: %s", err)
+	}
+	for _, sub := range s.subs {
+		if sub.ID > s.seq {
+			s.seq = sub.ID
+		}
+	}
+	return s, nil
+}
+
+// Add saves sub and returns it with assigned ID. It fails with ErrLimit
+// when the chat has MaxSubscriptions already.
+func (s *Store) Add(sub Subscription) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int
+	for i := range s.subs {
+		if s.subs[i].ChatID == sub.ChatID {
+			n++
+		}
+	}
+	if n >= MaxSubscriptions {
+		return Subscription{}, ErrLimit
+	}
+
+	sub.ID = s.seq + 1
+	sub.Fired = false
+	subs := append(s.subs[:len(s.subs):len(s.subs)], sub)
+	if err := s.save(subs); err != nil {
+		return Subscription{}, err
+	}
+	s.seq = sub.ID
+	return sub, nil
+}
+
+// List returns subscriptions of a chat.
+func (s *Store) List(chatID int64) []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var v []Subscription
+	for _, sub := range s.subs {
+		if sub.ChatID == chatID {
+			v = append(v, sub)
+		}
+	}
+	return v
+}
+
+// Delete removes a subscription of a chat.
+func (s *Store) Delete(chatID, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, sub := range s.subs {
+		if sub.ChatID == chatID && sub.ID == id {
+			subs := append(s.subs[:i:i], s.subs[i+1:]...)
+			return s.save(subs)
+		}
+	}
+	return ErrNotFound
+}
+
+// Check evaluates all subscriptions against ex and returns alerts for
+// subscriptions whose conditions started to hold.
+func (s *Store) Check(ex []bank.Ex) ([]Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var alerts []Alert
+	var changed bool
+
+	for i := range s.subs {
+		sub := &s.subs[i]
+		for _, e := range ex {
+			e, ok := sub.match(e)
+			if !ok {
+				continue
+			}
+			rate, ok := Rate(e, sub.Side)
+			if !ok {
+				break
+			}
+			holds := sub.Op.holds(rate, sub.Value)
+			if holds && !sub.Fired {
+				alerts = append(alerts, Alert{Subscription: *sub, Rate: rate})
+			}
+			if holds != sub.Fired {
+				sub.Fired = holds
+				changed = true
+			}
+			break
+		}
+	}
+
+	if !changed {
+		return alerts, nil
+	}
+	// Fired stays changed even when saving fails, so alerts are not
+	// repeated on every refresh.
+	return alerts, s.save(s.subs)
+}
+
+// save writes subs and makes them current.
+func (s *Store) save(subs []Subscription) error {
+	if err := jsonfile.Save(s.filename, subs); err != nil {
+		return fmt.Errorf("alert: %s", err)
+	}
+	s.subs = subs
+	return nil
+}
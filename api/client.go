@@ -2,11 +2,18 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/koorgoo/vtb24/money"
 )
 
 const (
@@ -14,16 +21,100 @@ const (
 	RequestURL  = "https://www.vtb24.ru/services/ExecuteAction"
 )
 
+// Client defaults.
+const (
+	DefaultTimeout     = 10 * time.Second
+	DefaultBackoff     = time.Second
+	DefaultMaxBodySize = 1 << 20
+)
+
+var (
+	ErrEmptyItems   = errors.New("api: empty items")
+	ErrBodyTooLarge = errors.New("api: response body too large")
+)
+
+// StatusError is returned when a server responds with non-200 status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string { return fmt.Sprintf("api: unexpected status %s", e.Status) }
+
+// Temporary reports whether a request may succeed if retried.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// DecodeError is returned when a response body is not valid JSON.
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string { return fmt.Sprintf("api: decode: %s", e.Err) }
+
 type Client struct {
 	Client *http.Client
+	// URL defaults to RequestURL.
+	URL string
+	// Timeout limits every attempt. Zero means DefaultTimeout.
+	Timeout time.Duration
+	// Retries is a number of attempts after the first failed one.
+	Retries int
+	// Backoff is a delay before the first retry. It doubles on every next
+	// retry. Zero means DefaultBackoff.
+	Backoff time.Duration
+	// MaxBodySize limits a response body. Zero means DefaultMaxBodySize.
+	MaxBodySize int64
 }
 
 func (c *Client) Request() (*Response, error) {
-	req := newRequest()
-	return doRequest(c.Client, req)
+	return c.RequestContext(context.Background())
+}
+
+// RequestContext requests rates retrying temporary failures with exponential
+// backoff.
+func (c *Client) RequestContext(ctx context.Context) (*Response, error) {
+	backoff := c.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.request(ctx)
+		if err == nil || attempt >= c.Retries || ctx.Err() != nil || !isTemporary(err) {
+			return resp, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) request(ctx context.Context) (*Response, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	url := c.URL
+	if url == "" {
+		url = RequestURL
+	}
+	req := newRequest(url).WithContext(ctx)
+
+	maxBodySize := c.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+	return doRequest(c.Client, req, maxBodySize)
 }
 
-func doRequest(client *http.Client, req *http.Request) (*Response, error) {
+func doRequest(client *http.Client, req *http.Request, maxBodySize int64) (*Response, error) {
 	if client == nil {
 		client = http.DefaultClient
 	}
@@ -31,14 +122,47 @@ func doRequest(client *http.Client, req *http.Request) (*Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	if int64(len(b)) > maxBodySize {
+		return nil, ErrBodyTooLarge
+	}
+
 	var rr *Response
-	err = json.NewDecoder(resp.Body).Decode(&rr)
-	return rr, err
+	if err = json.Unmarshal(b, &rr); err != nil {
+		return nil, &DecodeError{Err: err}
+	}
+	if rr == nil || len(rr.Items) == 0 {
+		return nil, ErrEmptyItems
+	}
+	return rr, nil
+}
+
+func isTemporary(err error) bool {
+	switch err := err.(type) {
+	case *StatusError:
+		return err.Temporary()
+	case *DecodeError:
+		return true
+	}
+	switch err {
+	case ErrEmptyItems, ErrBodyTooLarge:
+		return false
+	}
+	// Network errors and timeouts.
+	return true
 }
 
-func newRequest() *http.Request {
+func newRequest(url string) *http.Request {
 	b := bytes.NewReader([]byte(RequestBody))
-	r, err := http.NewRequest("POST", RequestURL, b)
+	r, err := http.NewRequest("POST", url, b)
 	if err != nil {
 		panic(err)
 	}
@@ -51,38 +175,65 @@ type Response struct {
 }
 
 type Item struct {
-	CurrencyGroupAbbr string    `json:"currencyGroupAbbr"`
-	CurrencyAbbr      string    `json:"currencyAbbr"`
-	Title             string    `json:"title"`
-	Quantity          float64   `json:"quantity"`
-	Buy               ItemValue `json:"buy"`
-	BuyArrow          string    `json:"buyArrow"`
-	Sell              ItemValue `json:"sell"`
-	SellArrow         string    `json:"sellArrow"`
-	Gradation         float64   `json:"gradation"`
-	DateActiveFrom    ItemTime  `json:"dateActiveFrom"`
-	IsMetal           bool      `json:"isMetal"`
+	CurrencyGroupAbbr string        `json:"currencyGroupAbbr"`
+	CurrencyAbbr      string        `json:"currencyAbbr"`
+	Title             string        `json:"title"`
+	Quantity          money.Decimal `json:"quantity"`
+	Buy               ItemValue     `json:"buy"`
+	BuyArrow          string        `json:"buyArrow"`
+	Sell              ItemValue     `json:"sell"`
+	SellArrow         string        `json:"sellArrow"`
+	Gradation         money.Decimal `json:"gradation"`
+	DateActiveFrom    ItemTime      `json:"dateActiveFrom"`
+	IsMetal           bool          `json:"isMetal"`
 }
 
-type ItemValue float64
+// ItemValue is a decimal value of vtb24.ru like "57,55".
+type ItemValue money.Decimal
+
+// ParseValue parses values like "57,55" or "57.55".
+func ParseValue(s string) (ItemValue, error) {
+	d, err := money.Parse(strings.Replace(s, ",", ".", 1))
+	return ItemValue(d), err
+}
+
+// MustValue is like ParseValue but panics when s is invalid. It simplifies
+// writing constant values.
+func MustValue(s string) ItemValue {
+	v, err := ParseValue(s)
+	if err != nil {
+		panic(fmt.Sprintf("api: invalid value %q", s))
+	}
+	return v
+}
+
+// Decimal returns v as a decimal.
+func (v ItemValue) Decimal() money.Decimal { return money.Decimal(v) }
 
 func (v *ItemValue) UnmarshalJSON(b []byte) error {
 	var s string
 	if err := json.Unmarshal(b, &s); err != nil {
 		return err
 	}
-	s = strings.Replace(s, ",", ".", 1)
-	f, err := strconv.ParseFloat(s, 64)
+	d, err := ParseValue(s)
 	if err != nil {
 		return err
 	}
-	v2 := ItemValue(f)
-	*v = v2
+	*v = d
 	return nil
 }
 
+// MarshalJSON implements json.Marshaler interface. The value is encoded as
+// a string the way vtb24.ru does.
+func (v ItemValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Decimal().String())
+}
+
 type ItemTime time.Time
 
+// Moscow is a time zone rates are published in.
+var Moscow = time.FixedZone("МСК", 3*60*60)
+
 const (
 	timePrefix = "/Date("
 	timeSuffix = ")/"
@@ -99,9 +250,15 @@ func (d *ItemTime) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return err
 	}
-	var secs int64 = n / 1000
-	var nsecs int64 = n - secs*1000
-	d2 := time.Unix(secs, nsecs).UTC()
-	*d = ItemTime(d2)
+	// n is milliseconds since the epoch. Nanoseconds of zero time overflow
+	// int64, so seconds are split off.
+	*d = ItemTime(time.Unix(n/1000, n%1000*int64(time.Millisecond)).UTC())
 	return nil
 }
+
+// MarshalJSON implements json.Marshaler interface.
+func (d ItemTime) MarshalJSON() ([]byte, error) {
+	t := time.Time(d)
+	n := t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)
+	return json.Marshal(timePrefix + strconv.FormatInt(n, 10) + timeSuffix)
+}
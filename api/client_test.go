@@ -1,20 +1,28 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/koorgoo/vtb24/money"
 )
 
 func TestItemValue_UnmarshalJSON(t *testing.T) {
 	tests := []struct {
 		JSON  string
-		Value float64
+		Value money.Decimal
 	}{
-		{`"0"`, 0},
-		{`"12"`, 12},
-		{`"12,34"`, 12.34},
-		{`"12.34"`, 12.34},
+		{`"0"`, money.New(0, 0)},
+		{`"12"`, money.New(12, 0)},
+		{`"12,34"`, money.New(1234, 2)},
+		{`"12.34"`, money.New(1234, 2)},
 	}
 
 	for _, tt := range tests {
@@ -23,8 +31,8 @@ func TestItemValue_UnmarshalJSON(t *testing.T) {
 			if err := json.Unmarshal([]byte(tt.JSON), &v); err != nil {
 				t.Fatal(err)
 			}
-			if v2 := ItemValue(tt.Value); v != v2 {
-				t.Errorf("want %s, got %s", v2, v)
+			if v.Decimal().Cmp(tt.Value) != 0 {
+				t.Errorf("want %v, got %v", tt.Value, v.Decimal())
 			}
 		})
 	}
@@ -37,7 +45,7 @@ func TestItemTime_UnmarshalJSON(t *testing.T) {
 	}{
 		{
 			`"/Date(1506453186593)/"`,
-			time.Date(2017, time.September, 26, 19, 13, 6, 593, time.UTC),
+			time.Date(2017, time.September, 26, 19, 13, 6, 593*int(time.Millisecond), time.UTC),
 		},
 	}
 
@@ -50,6 +58,144 @@ func TestItemTime_UnmarshalJSON(t *testing.T) {
 			if v := time.Time(v); tt.Time != v {
 				t.Errorf("want %s, got %s", tt.Time, v)
 			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.JSON {
+				t.Errorf("want %s, got %s", tt.JSON, b)
+			}
 		})
 	}
 }
+
+func TestParsePair(t *testing.T) {
+	tests := []struct {
+		Pair     string
+		Src, Dst string
+		OK       bool
+	}{
+		{"USD/RUB", USD, RUB, true},
+		{"usd", USD, RUB, true},
+		{"EUR/USD", EUR, USD, true},
+		{"RUB", "", "", false},
+		{"US/RUB", "", "", false},
+		{"EUR/USD/RUB", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Pair, func(t *testing.T) {
+			src, dst, err := ParsePair(tt.Pair)
+			if ok := (err == nil); ok != tt.OK {
+				t.Fatalf("error: want %v, got %v: %v", tt.OK, ok, err)
+			}
+			if src != tt.Src || dst != tt.Dst {
+				t.Errorf("want %s/%s, got %s/%s", tt.Src, tt.Dst, src, dst)
+			}
+		})
+	}
+}
+
+const testBody = `{"items":[{"currencyGroupAbbr":"tele","currencyAbbr":"USD","buy":"57,5","sell":"59,5"}]}`
+
+func TestClient_RequestContext(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Responses []string // "status body"
+		Retries   int
+		Err       func(error) bool
+		Calls     int
+	}{
+		{"ok", []string{"200 " + testBody}, 0, isNil, 1},
+		{"retry 500", []string{"500 ", "502 ", "200 " + testBody}, 2, isNil, 3},
+		{"retries exceeded", []string{"500 ", "500 "}, 1, isStatus(500), 2},
+		{"no retry 404", []string{"404 ", "200 " + testBody}, 2, isStatus(404), 1},
+		{"decode", []string{"200 <html>"}, 0, isDecode, 1},
+		{"null", []string{"200 null"}, 0, is(ErrEmptyItems), 1},
+		{"empty items", []string{`200 {"items":[]}`}, 0, is(ErrEmptyItems), 1},
+		{"too large", []string{"200 " + testBody + strings.Repeat(" ", 100)}, 0, is(ErrBodyTooLarge), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var calls int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				a := strings.SplitN(tt.Responses[calls], " ", 2)
+				calls++
+				code, _ := strconv.Atoi(a[0])
+				w.WriteHeader(code)
+				io.WriteString(w, a[1])
+			}))
+			defer srv.Close()
+
+			c := &Client{URL: srv.URL, Retries: tt.Retries, Backoff: time.Millisecond, MaxBodySize: int64(len(testBody) + 10)}
+			resp, err := c.RequestContext(context.Background())
+			if !tt.Err(err) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err == nil && len(resp.Items) != 1 {
+				t.Errorf("want 1 item, got %d", len(resp.Items))
+			}
+			if calls != tt.Calls {
+				t.Errorf("want %d calls, got %d", tt.Calls, calls)
+			}
+		})
+	}
+}
+
+func TestClient_RequestContext_timeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	c := &Client{URL: srv.URL, Timeout: 10 * time.Millisecond}
+	if _, err := c.RequestContext(context.Background()); err == nil {
+		t.Fatal("want timeout error")
+	}
+}
+
+func isNil(err error) bool { return err == nil }
+
+func is(target error) func(error) bool {
+	return func(err error) bool { return err == target }
+}
+
+func isStatus(code int) func(error) bool {
+	return func(err error) bool {
+		e, ok := err.(*StatusError)
+		return ok && e.StatusCode == code
+	}
+}
+
+func isDecode(err error) bool {
+	_, ok := err.(*DecodeError)
+	return ok
+}
+
+func TestResponse_roundTrip(t *testing.T) {
+	var resp Response
+	if err := json.Unmarshal([]byte(testBody), &resp); err != nil {
+		t.Fatal(err)
+	}
+	resp.Items[0].DateActiveFrom = ItemTime(time.Date(2017, time.September, 26, 19, 13, 6, 593*int(time.Millisecond), time.UTC))
+
+	b, err := json.Marshal(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp2 Response
+	if err := json.Unmarshal(b, &resp2); err != nil {
+		t.Fatal(err)
+	}
+	b2, err := json.Marshal(&resp2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(b2) {
+		t.Errorf("want %s, got %s", b, b2)
+	}
+}
@@ -23,6 +23,23 @@ const (
 	USD = "USD" // United States dollar
 )
 
+// Precious metals are quoted per gram.
+const (
+	XAG = "XAG" // Silver
+	XAU = "XAU" // Gold
+	XPD = "XPD" // Palladium
+	XPT = "XPT" // Platinum
+)
+
+// IsMetal reports whether code is a precious metal.
+func IsMetal(code string) bool {
+	switch code {
+	case XAG, XAU, XPD, XPT:
+		return true
+	}
+	return false
+}
+
 // SplitCurrency returns a list of currencies from abbr.
 // Empty dest means RUB.
 func SplitCurrency(abbr string) (src, dest string) {
@@ -36,3 +53,32 @@ func SplitCurrency(abbr string) (src, dest string) {
 		panic(fmt.Sprintf("too many currencies in %q", abbr))
 	}
 }
+
+// ParsePair parses a currency pair like "USD/RUB" or "USD". Empty dst means
+// RUB.
+func ParsePair(pair string) (src, dst string, err error) {
+	a := strings.Split(strings.ToUpper(pair), "/")
+	if len(a) > 2 {
+		return "", "", fmt.Errorf("invalid currency pair %q", pair)
+	}
+	src, dst = a[0], RUB
+	if len(a) == 2 {
+		dst = a[1]
+	}
+	if !isCode(src) || !isCode(dst) || src == dst {
+		return "", "", fmt.Errorf("invalid currency pair %q", pair)
+	}
+	return src, dst, nil
+}
+
+func isCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
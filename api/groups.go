@@ -1,7 +1,5 @@
 package api
 
-import "fmt"
-
 // Typical currency group abbreviations.
 const (
 	GroupCash        = "cash"
@@ -13,24 +11,25 @@ const (
 	// GroupSpecKassaCash  = "pp_curcur_speckassa_cash"
 	GroupTele = "tele"
 	// GroupW4   = "w4"
+
+	// GroupMetal is a group of precious metal accounts.
+	GroupMetal = "metal"
+
+	// GroupCBR is a group of official rates of the Central Bank of Russia.
+	GroupCBR = "cbr"
 )
 
-// GroupText returns a text in Russian for provided currency group.
-func GroupText(group string) string {
-	if text, ok := groupText[group]; ok {
-		return text
-	}
-	return fmt.Sprintf("unknown group %q", group)
+// IsGroup reports whether group is a known currency group.
+func IsGroup(group string) bool {
+	return groups[group]
 }
 
-var groupText = map[string]string{
-	"cash":         "в офисе, наличные",
-	"cash-desk":    "в спецкассе",
-	"central-bank": "в офисе, безналичные",
-	// "central-bank-jur":          "",
-	// "pp_curcur_office_cash":     "",
-	// "pp_curcur_office_cashless": "",
-	// "pp_curcur_speckassa_cash":  "",
-	"tele": "в ВТБ24 - онлайн",
-	// "w4":   "",
+// groups are known currency groups. Texts of groups are in package locale.
+var groups = map[string]bool{
+	GroupCash:        true,
+	GroupCashDesk:    true,
+	GroupCentralBank: true,
+	GroupTele:        true,
+	GroupMetal:       true,
+	GroupCBR:         true,
 }
@@ -0,0 +1,89 @@
+package bank
+
+import "strings"
+
+// Direction is a direction of a rate change.
+type Direction int
+
+const (
+	Flat Direction = 0
+	Up   Direction = 1
+	Down Direction = -1
+)
+
+// parseArrow parses api.Item.BuyArrow and api.Item.SellArrow.
+func parseArrow(s string) Direction {
+	switch strings.ToLower(s) {
+	case "up":
+		return Up
+	case "down":
+		return Down
+	}
+	return Flat
+}
+
+// Change is a change of a base rate since previous rates.
+type Change struct {
+	Direction Direction
+	// Delta and Percent are zero when previous rates are unknown.
+	Delta   float64
+	Percent float64
+}
+
+func newChange(rate, prev float64, d Direction) Change {
+	if prev == 0 || rate == prev {
+		return Change{Direction: d}
+	}
+	c := Change{Delta: rate - prev, Percent: (rate - prev) / prev * 100}
+	if c.Delta > 0 {
+		c.Direction = Up
+	} else {
+		c.Direction = Down
+	}
+	return c
+}
+
+// WithPrevious returns exchanges of v knowing base rates of prev, so Change
+// reports deltas. A rate equal to the previous one keeps the delta of its
+// last change.
+func WithPrevious(v, prev []Ex) []Ex {
+	m := map[string]*ex{}
+	for _, e := range prev {
+		if p, ok := e.(*ex); ok {
+			m[exKey(p)] = p
+		}
+	}
+
+	a := make([]Ex, len(v))
+	for i, e := range v {
+		a[i] = e
+		x, ok := e.(*ex)
+		if !ok {
+			continue
+		}
+		p, ok := m[exKey(x)]
+		if !ok {
+			continue
+		}
+		buy, sell, ok := BaseRates(x)
+		pbuy, psell, pok := BaseRates(p)
+		if !ok || !pok {
+			continue
+		}
+
+		c := *x
+		c.prevBuy, c.prevSell = pbuy, psell
+		if buy == pbuy {
+			c.prevBuy = p.prevBuy
+		}
+		if sell == psell {
+			c.prevSell = p.prevSell
+		}
+		a[i] = &c
+	}
+	return a
+}
+
+func exKey(e *ex) string {
+	return e.provider + "/" + e.group + "/" + e.src + "/" + e.dst
+}
@@ -0,0 +1,55 @@
+package bank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+)
+
+func makeChangeEx(buy, sell string, buyArrow, sellArrow string) []Ex {
+	return ParseEx(&api.Response{
+		Items: []*api.Item{
+			{
+				CurrencyGroupAbbr: api.GroupTele,
+				CurrencyAbbr:      api.USD,
+				Buy:               api.MustValue(buy),
+				BuyArrow:          buyArrow,
+				Sell:              api.MustValue(sell),
+				SellArrow:         sellArrow,
+			},
+		},
+	}, time.Time{})
+}
+
+func TestWithPrevious(t *testing.T) {
+	first := makeChangeEx("64", "80", "", "")
+	second := WithPrevious(makeChangeEx("72", "80", "", ""), first)
+	third := WithPrevious(makeChangeEx("72", "80", "", ""), second)
+
+	tests := []struct {
+		Name string
+		Ex   Ex
+		Buy  Change
+		Sell Change
+	}{
+		{"arrows", makeChangeEx("64", "80", "up", "Down")[0], Change{Direction: Up}, Change{Direction: Down}},
+		{"first", first[0], Change{}, Change{}},
+		{"changed", second[0], Change{Up, 8, 12.5}, Change{}},
+		{"unchanged", third[0], Change{Up, 8, 12.5}, Change{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			buy, sell := tt.Ex.Change()
+			if buy != tt.Buy || sell != tt.Sell {
+				t.Errorf("want %+v %+v, got %+v %+v", tt.Buy, tt.Sell, buy, sell)
+			}
+		})
+	}
+
+	// Inverted sell rate is 1/buy and goes down when buy goes up.
+	buy, sell := Invert(second[0]).Change()
+	if buy.Direction != Flat || sell.Direction != Down {
+		t.Errorf("inverted: want flat and down, got %+v %+v", buy, sell)
+	}
+}
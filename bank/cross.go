@@ -0,0 +1,101 @@
+package bank
+
+import (
+	"time"
+
+	"github.com/koorgoo/vtb24/exchange"
+)
+
+// Cross returns a synthetic exchange of a.Src() to b.Src() through their
+// common dst currency, e.g. USD/RUB and EUR/RUB give USD/EUR. Buying USD/EUR
+// means the bank buys USD for RUB and sells EUR for the RUB.
+//
+// Cross returns false when a and b have different dst currencies or groups.
+func Cross(a, b Ex) (Ex, bool) {
+	if a.Dst() != b.Dst() || a.Group() != b.Group() || a.Provider() != b.Provider() || a.Src() == b.Src() {
+		return nil, false
+	}
+	return &cross{
+		ex: ex{
+			src:        a.Src(),
+			dst:        b.Src(),
+			group:      a.Group(),
+			provider:   a.Provider(),
+			metal:      a.IsMetal() || b.IsMetal(),
+			activeFrom: later(a.ActiveFrom(), b.ActiveFrom()),
+			fetched:    earlier(a.FetchedAt(), b.FetchedAt()),
+			Interface:  exchange.Compose(a, Invert(b)),
+		},
+		a: a,
+		b: b,
+	}, true
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// earlier returns the earlier of non-zero a and b.
+func earlier(a, b time.Time) time.Time {
+	if a.IsZero() || !b.IsZero() && b.Before(a) {
+		return b
+	}
+	return a
+}
+
+type cross struct {
+	ex
+	a, b Ex
+}
+
+func (c *cross) invert() Ex {
+	e, _ := Cross(c.b, c.a)
+	return e
+}
+
+// Via returns a currency a synthetic exchange goes through. It returns false
+// for exchanges quoted by a bank directly.
+func Via(e Ex) (string, bool) {
+	if c, ok := e.(*cross); ok {
+		return c.a.Dst(), true
+	}
+	return "", false
+}
+
+// CrossEx returns synthetic exchanges of src to dst through via currency for
+// every group quoting both src/via and dst/via.
+func CrossEx(v []Ex, src, dst, via string) []Ex {
+	legs := map[string][2]Ex{}
+	var groups []string
+	for _, e := range v {
+		if e.Dst() != via {
+			continue
+		}
+		l, ok := legs[e.Group()]
+		if !ok {
+			groups = append(groups, e.Group())
+		}
+		switch e.Src() {
+		case src:
+			l[0] = e
+		case dst:
+			l[1] = e
+		}
+		legs[e.Group()] = l
+	}
+
+	var a []Ex
+	for _, group := range groups {
+		l := legs[group]
+		if l[0] == nil || l[1] == nil {
+			continue
+		}
+		if e, ok := Cross(l[0], l[1]); ok {
+			a = append(a, e)
+		}
+	}
+	return a
+}
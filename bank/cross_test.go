@@ -0,0 +1,53 @@
+package bank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+)
+
+func TestCrossEx(t *testing.T) {
+	ex := ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("64"), Sell: api.MustValue("80")},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.EUR, Buy: api.MustValue("80"), Sell: api.MustValue("128")},
+			{CurrencyGroupAbbr: api.GroupCash, CurrencyAbbr: api.USD, Buy: api.MustValue("59"), Sell: api.MustValue("65")},
+		},
+	}, time.Time{})
+
+	v := CrossEx(ex, api.USD, api.EUR, api.RUB)
+	if len(v) != 1 {
+		t.Fatalf("want 1 cross, got %d", len(v))
+	}
+	e := v[0]
+	if e.Src() != api.USD || e.Dst() != api.EUR || e.Group() != api.GroupTele {
+		t.Fatalf("want USD/EUR tele, got %s/%s %s", e.Src(), e.Dst(), e.Group())
+	}
+	if via, ok := Via(e); !ok || via != api.RUB {
+		t.Errorf("want via RUB, got %q", via)
+	}
+
+	tests := []struct {
+		Name   string
+		Ex     Ex
+		Buy    float64
+		Sell   float64
+		Amount float64
+	}{
+		// 100 USD = 6400 RUB = 50 EUR; 100 USD cost 8000 RUB = 100 EUR.
+		{"direct", e, 50, 100, 100},
+		// 100 EUR = 8000 RUB = 100 USD; 100 EUR cost 12800 RUB = 200 USD.
+		{"inverted", Invert(e), 100, 200, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if n, err := tt.Ex.Buy(tt.Amount); err != nil || n != tt.Buy {
+				t.Errorf("buy: want %v, got %v: %v", tt.Buy, n, err)
+			}
+			if n, err := tt.Ex.Sell(tt.Amount); err != nil || n != tt.Sell {
+				t.Errorf("sell: want %v, got %v: %v", tt.Sell, n, err)
+			}
+		})
+	}
+}
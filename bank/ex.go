@@ -2,9 +2,11 @@ package bank
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/koorgoo/vtb24/api"
 	"github.com/koorgoo/vtb24/exchange"
+	"github.com/koorgoo/vtb24/money"
 )
 
 type Ex interface {
@@ -14,54 +16,140 @@ type Ex interface {
 	Dst() string
 	// Group returns a currency group affecting rates.
 	Group() string
+	// IsMetal reports whether src or dst is a precious metal. Metals are
+	// quoted per gram.
+	IsMetal() bool
+	// Provider returns a name of a provider quoting rates.
+	Provider() string
+	// ActiveFrom returns time rates are active from. It is zero when a
+	// provider does not tell it.
+	ActiveFrom() time.Time
+	// FetchedAt returns time rates were fetched at.
+	FetchedAt() time.Time
+	// Change returns changes of base buy and sell rates.
+	Change() (buy, sell Change)
 
 	exchange.Interface
 }
 
+// NewEx returns an exchange of src to dst quoted by provider.
+func NewEx(src, dst, group, provider string, activeFrom, fetched time.Time, e exchange.Interface) Ex {
+	metal := api.IsMetal(src) || api.IsMetal(dst)
+	return &ex{
+		src:        src,
+		dst:        dst,
+		group:      group,
+		provider:   provider,
+		metal:      metal,
+		activeFrom: activeFrom,
+		fetched:    fetched,
+		Interface:  e,
+	}
+}
+
 type ex struct {
-	src, dst, group string
+	src, dst, group     string
+	provider            string
+	metal               bool
+	activeFrom, fetched time.Time
+	// Directions reported by a bank and base rates of previous snapshots.
+	buyDir, sellDir   Direction
+	prevBuy, prevSell float64
+	// tiersErr tells why gradations of a bank are invalid.
+	tiersErr error
 	exchange.Interface
 }
 
-func (e *ex) Src() string   { return e.src }
-func (e *ex) Dst() string   { return e.dst }
-func (e *ex) Group() string { return e.group }
+func (e *ex) Src() string           { return e.src }
+func (e *ex) Dst() string           { return e.dst }
+func (e *ex) Group() string         { return e.group }
+func (e *ex) IsMetal() bool         { return e.metal }
+func (e *ex) Provider() string      { return e.provider }
+func (e *ex) ActiveFrom() time.Time { return e.activeFrom }
+func (e *ex) FetchedAt() time.Time  { return e.fetched }
 
+func (e *ex) Change() (buy, sell Change) {
+	b, s, _ := BaseRates(e)
+	return newChange(b, e.prevBuy, e.buyDir), newChange(s, e.prevSell, e.sellDir)
+}
+
+// String returns e like "USD/RUB tele" for logs. Texts shown to users are
+// made by package chat.
 func (e *ex) String() string {
-	group := api.GroupText(e.group)
-	return fmt.Sprintf("%s › %s (%s)", e.src, e.dst, group)
+	return fmt.Sprintf("%s/%s %s", e.src, e.dst, e.group)
 }
 
-func ParseEx(resp *api.Response) []Ex {
-	// Group rates by src, dst, and group.
-	m := map[string]map[string]map[string][]exchange.Rate{}
+// ParseEx returns exchanges of resp fetched at fetched time. Validator
+// rejects exchanges with invalid gradations.
+func ParseEx(resp *api.Response, fetched time.Time) []Ex {
+	type key struct{ src, dst, group string }
+	// Tier tables of buy and sell rates by src, dst, and group.
+	var keys []key
+	buy, sell := map[key]exchange.Tiers{}, map[key]exchange.Tiers{}
+	// Gradations of a group may be updated at different times, the latest
+	// one is active from.
+	active := map[key]time.Time{}
+	// Arrows of the lowest gradation are arrows of base rates.
+	base := map[key]*api.Item{}
+	metals := map[string]bool{}
 	for _, item := range resp.Items {
 		src, dst := api.SplitCurrency(item.CurrencyAbbr)
 		if dst == "" {
 			dst = api.RUB
 		}
-		if _, ok := m[src]; !ok {
-			m[src] = map[string]map[string][]exchange.Rate{}
+		if item.IsMetal || api.IsMetal(src) {
+			metals[src] = true
 		}
-		if _, ok := m[src][dst]; !ok {
-			m[src][dst] = map[string][]exchange.Rate{}
+		// Some currencies (e.g. JPY) are quoted per Quantity units.
+		q := item.Quantity
+		if q.Sign() <= 0 {
+			q = money.New(1, 0)
 		}
 		group := item.CurrencyGroupAbbr
-		m[src][dst][group] = append(m[src][dst][group], exchange.Rate{
-			Buy:       float64(item.Buy),
-			Sell:      float64(item.Sell),
-			Threshold: exchange.NewThreshold(item.Gradation, item.Gradation),
-		})
+		if group == "" && metals[src] {
+			group = api.GroupMetal
+		}
+		k := key{src, dst, group}
+		if _, ok := buy[k]; !ok {
+			keys = append(keys, k)
+		}
+		from := item.Gradation
+		buy[k] = append(buy[k], exchange.Tier{From: from, Rate: item.Buy.Decimal().Div(q)})
+		sell[k] = append(sell[k], exchange.Tier{From: from, Rate: item.Sell.Decimal().Div(q)})
+		if t := time.Time(item.DateActiveFrom); t.After(active[k]) {
+			active[k] = t
+		}
+		if b, ok := base[k]; !ok || item.Gradation.Cmp(b.Gradation) < 0 {
+			base[k] = item
+		}
 	}
 
 	var v []Ex
-	for src := range m {
-		for dst := range m[src] {
-			for group, rates := range m[src][dst] {
-				e := exchange.New(rates...)
-				v = append(v, &ex{src: src, dst: dst, group: group, Interface: e})
+	for _, k := range keys {
+		// Gradations are chained, so only duplicates fail. Of duplicates the
+		// first one is used.
+		e, err := exchange.NewTiered(buy[k].Chain(), sell[k].Chain())
+		if err != nil {
+			var rates []exchange.Rate
+			for i, b := range buy[k] {
+				s := sell[k][i]
+				rates = append(rates, exchange.NewRate(b.Rate, s.Rate, exchange.NewDecimalThreshold(b.From, s.From)))
 			}
+			e = exchange.New(rates...)
 		}
+		v = append(v, &ex{
+			src:        k.src,
+			dst:        k.dst,
+			group:      k.group,
+			provider:   ProviderVTB,
+			metal:      metals[k.src] || metals[k.dst],
+			activeFrom: active[k],
+			fetched:    fetched,
+			buyDir:     parseArrow(base[k].BuyArrow),
+			sellDir:    parseArrow(base[k].SellArrow),
+			tiersErr:   err,
+			Interface:  e,
+		})
 	}
 	return v
 }
@@ -101,6 +189,47 @@ func WithSrcDst(srcdst ...string) ExFilter {
 	}
 }
 
+// WithPairs keeps exchanges of currency pairs like "USD/RUB". Invalid pairs
+// are ignored.
+func WithPairs(pairs ...string) ExFilter {
+	var srcdst []string
+	for _, pair := range pairs {
+		if src, dst, err := api.ParsePair(pair); err == nil {
+			srcdst = append(srcdst, src, dst)
+		}
+	}
+	return WithSrcDst(srcdst...)
+}
+
+// WithMetals keeps exchanges of precious metals.
+func WithMetals() ExFilter {
+	return func(e Ex) bool { return e.IsMetal() }
+}
+
+// AnyOf keeps exchanges matching at least one of filters.
+func AnyOf(filters ...ExFilter) ExFilter {
+	return func(e Ex) bool {
+		for _, filter := range filters {
+			if filter(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithProvider keeps exchanges quoted by providers.
+func WithProvider(names ...string) ExFilter {
+	return func(e Ex) bool {
+		for i := range names {
+			if e.Provider() == names[i] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 func WithGroup(groups ...string) ExFilter {
 	return func(e Ex) bool {
 		for i := range groups {
@@ -113,6 +242,78 @@ func WithGroup(groups ...string) ExFilter {
 }
 
 func Invert(e Ex) Ex {
-	i := exchange.Invert(e)
-	return &ex{src: e.Dst(), dst: e.Src(), group: e.Group(), Interface: i}
+	if c, ok := e.(*cross); ok {
+		return c.invert()
+	}
+	i := &ex{
+		src:        e.Dst(),
+		dst:        e.Src(),
+		group:      e.Group(),
+		provider:   e.Provider(),
+		metal:      e.IsMetal(),
+		activeFrom: e.ActiveFrom(),
+		fetched:    e.FetchedAt(),
+		Interface:  exchange.Invert(e),
+	}
+	// The bank buys dst for inverted src when it sells src.
+	if x, ok := e.(*ex); ok {
+		i.buyDir, i.sellDir = -x.sellDir, -x.buyDir
+		i.prevBuy, i.prevSell = inverse(x.prevSell), inverse(x.prevBuy)
+	}
+	return i
+}
+
+func inverse(v float64) float64 {
+	if v == 0 {
+		return 0
+	}
+	return 1 / v
+}
+
+// Snapshot is a set of exchanges fetched at once.
+type Snapshot struct {
+	Ex   []Ex
+	Time time.Time
+	// Restored is true for a snapshot loaded from disk on start.
+	Restored bool
+}
+
+// Find returns exchanges of src to dst in all groups. Inverted exchanges are
+// used when a bank quotes dst/src only.
+func Find(v []Ex, src, dst string) []Ex {
+	var a []Ex
+	for _, e := range v {
+		switch {
+		case e.Src() == src && e.Dst() == dst:
+			a = append(a, e)
+		case e.Src() == dst && e.Dst() == src:
+			a = append(a, Invert(e))
+		}
+	}
+	return a
+}
+
+// BaseRates returns rates of e for the lowest thresholds, i.e. rates for
+// small amounts.
+func BaseRates(e Ex) (buy, sell float64, ok bool) {
+	b, s := e.Tiers()
+	if len(b) == 0 || len(s) == 0 {
+		return 0, 0, false
+	}
+	return b[0].Rate.Float(), s[0].Rate.Float(), true
+}
+
+// Markup returns markups of e over official rates in percent. Buy markup
+// shows how cheaper the bank buys src, sell markup shows how more expensive
+// it sells src.
+func Markup(e, official Ex) (buy, sell float64, ok bool) {
+	b, s, ok := BaseRates(e)
+	if !ok {
+		return
+	}
+	ob, os, ok := BaseRates(official)
+	if !ok || ob == 0 || os == 0 {
+		return 0, 0, false
+	}
+	return (ob - b) / ob * 100, (s - os) / os * 100, true
 }
@@ -0,0 +1,35 @@
+package bank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/money"
+)
+
+func TestParseEx_tiers(t *testing.T) {
+	v := ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("57"), Sell: api.MustValue("59")},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("58"), Sell: api.MustValue("58.5"), Gradation: money.New(1000, 0)},
+			// EUR quotes the same gradation twice.
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.EUR, Buy: api.MustValue("67"), Sell: api.MustValue("70"), Gradation: money.New(1000, 0)},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.EUR, Buy: api.MustValue("68"), Sell: api.MustValue("69"), Gradation: money.New(1000, 0)},
+		},
+	}, time.Time{})
+	if len(v) != 2 || v[0].Src() != api.USD {
+		t.Fatalf("want USD and EUR, got %v", v)
+	}
+	if err := v[1].(*ex).tiersErr; err == nil {
+		t.Errorf("want EUR tiers error")
+	}
+
+	buy, sell := v[0].Tiers()
+	if len(buy) != 2 || len(sell) != 2 || buy[1].From.Float() != 1000 || sell[1].Rate.Float() != 58.5 {
+		t.Errorf("want tiers from 0 and 1000, got %v and %v", buy, sell)
+	}
+	if n, err := v[0].Buy(1000); err != nil || n != 58000 {
+		t.Errorf("want 58000, got %v, %v", n, err)
+	}
+}
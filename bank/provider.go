@@ -0,0 +1,44 @@
+package bank
+
+import (
+	"context"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+)
+
+// ProviderVTB is a name of VTB24 provider.
+const ProviderVTB = "vtb24"
+
+// Provider fetches exchanges quoted by a bank.
+type Provider interface {
+	Name() string
+	Ex(ctx context.Context) ([]Ex, error)
+}
+
+// VTB is a Provider of VTB24 rates.
+type VTB struct {
+	Client *api.Client
+
+	last *api.Response
+}
+
+var _ Provider = (*VTB)(nil)
+
+func (p *VTB) Name() string { return ProviderVTB }
+
+func (p *VTB) Ex(ctx context.Context) ([]Ex, error) {
+	c := p.Client
+	if c == nil {
+		c = new(api.Client)
+	}
+	resp, err := c.RequestContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.last = resp
+	return ParseEx(resp, time.Now()), nil
+}
+
+// Last returns a response fetched by the last successful call of Ex.
+func (p *VTB) Last() *api.Response { return p.last }
@@ -0,0 +1,164 @@
+package bank
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/koorgoo/vtb24/api"
+)
+
+// Reasons of ValidationError.
+const (
+	ReasonMissing  = "missing"
+	ReasonInverted = "inverted"
+	ReasonInvalid  = "invalid_rate"
+	ReasonJump     = "jump"
+	ReasonTiers    = "tiers"
+)
+
+// ValidationError describes why a snapshot is rejected.
+type ValidationError struct {
+	Reason string
+	Text   string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("bank: invalid snapshot: %s", e.Text)
+}
+
+func invalid(reason, format string, a ...interface{}) error {
+	return &ValidationError{Reason: reason, Text: fmt.Sprintf(format, a...)}
+}
+
+// DefaultMaxRate is a rate considered absurd.
+const DefaultMaxRate = 1e5
+
+// Validator rejects snapshots which look broken. It checks exchanges of
+// Provider only.
+//
+// A snapshot rejected because of a rate jump is accepted when the next
+// snapshot confirms the jump. Validator is not safe for concurrent use.
+type Validator struct {
+	Provider string
+	// Pairs must be quoted in at least one group.
+	Pairs []string
+	// Groups must quote at least one pair.
+	Groups []string
+	// MaxJump is a maximal change of a rate in percent compared to the
+	// previous snapshot. Zero disables the check.
+	MaxJump float64
+	// MaxRate defaults to DefaultMaxRate.
+	MaxRate float64
+
+	pending []Ex
+}
+
+// Validate checks next snapshot against prev one, which may be nil.
+func (v *Validator) Validate(prev, next []Ex) error {
+	next = FilterEx(next, WithProvider(v.Provider))
+	if err := v.validateRates(next); err != nil {
+		return err
+	}
+	if err := v.validatePresence(next); err != nil {
+		return err
+	}
+
+	prev = FilterEx(prev, WithProvider(v.Provider))
+	err := v.validateJump(prev, next)
+	if err != nil && v.pending != nil && v.validateJump(v.pending, next) == nil {
+		err = nil
+	}
+	if err != nil {
+		v.pending = next
+		return err
+	}
+	v.pending = nil
+	return nil
+}
+
+func (v *Validator) validateRates(next []Ex) error {
+	maxRate := v.MaxRate
+	if maxRate <= 0 {
+		maxRate = DefaultMaxRate
+	}
+	for _, e := range next {
+		if x, ok := e.(*ex); ok && x.tiersErr != nil {
+			return invalid(ReasonTiers, "%s/%s %s: %s", e.Src(), e.Dst(), e.Group(), x.tiersErr)
+		}
+		for _, r := range e.Rates() {
+			for _, x := range []float64{r.Buy, r.Sell} {
+				if x <= 0 || x > maxRate || math.IsNaN(x) || math.IsInf(x, 0) {
+					return invalid(ReasonInvalid, "%s/%s %s: rate %v", e.Src(), e.Dst(), e.Group(), x)
+				}
+			}
+			if r.Buy > r.Sell {
+				return invalid(ReasonInverted, "%s/%s %s: buy %v > sell %v", e.Src(), e.Dst(), e.Group(), r.Buy, r.Sell)
+			}
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validatePresence(next []Ex) error {
+	pairs := map[string]bool{}
+	groups := map[string]bool{}
+	for _, e := range next {
+		pairs[e.Src()+"/"+e.Dst()] = true
+		groups[e.Group()] = true
+	}
+	for _, pair := range v.Pairs {
+		src, dst, err := api.ParsePair(pair)
+		if err != nil {
+			continue
+		}
+		if !pairs[src+"/"+dst] {
+			return invalid(ReasonMissing, "no %s/%s", src, dst)
+		}
+	}
+	for _, group := range v.Groups {
+		if !groups[group] {
+			return invalid(ReasonMissing, "no group %s", group)
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validateJump(prev, next []Ex) error {
+	if v.MaxJump <= 0 {
+		return nil
+	}
+	type key struct{ src, dst, group string }
+	m := map[key]Ex{}
+	for _, e := range prev {
+		m[key{e.Src(), e.Dst(), e.Group()}] = e
+	}
+	for _, e := range next {
+		p, ok := m[key{e.Src(), e.Dst(), e.Group()}]
+		if !ok {
+			continue
+		}
+		pb, ps, ok := BaseRates(p)
+		if !ok {
+			continue
+		}
+		nb, ns, ok := BaseRates(e)
+		if !ok {
+			continue
+		}
+		if d := change(pb, nb); d > v.MaxJump {
+			return invalid(ReasonJump, "%s/%s %s: buy changed by %.1f%%", e.Src(), e.Dst(), e.Group(), d)
+		}
+		if d := change(ps, ns); d > v.MaxJump {
+			return invalid(ReasonJump, "%s/%s %s: sell changed by %.1f%%", e.Src(), e.Dst(), e.Group(), d)
+		}
+	}
+	return nil
+}
+
+// change returns an absolute change of y relative to x in percent.
+func change(x, y float64) float64 {
+	if x == 0 {
+		return 0
+	}
+	return math.Abs(y-x) / x * 100
+}
@@ -0,0 +1,86 @@
+package bank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/money"
+)
+
+func testEx(usdBuy, usdSell string) []Ex {
+	return ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue(usdBuy), Sell: api.MustValue(usdSell)},
+			{CurrencyGroupAbbr: api.GroupCash, CurrencyAbbr: api.EUR, Buy: api.MustValue("67"), Sell: api.MustValue("70")},
+		},
+	}, time.Time{})
+}
+
+// duplicateTiers quotes USD twice for the same gradation.
+func duplicateTiers() []Ex {
+	return ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("57"), Sell: api.MustValue("59"), Gradation: money.New(1000, 0)},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("58"), Sell: api.MustValue("60"), Gradation: money.New(1000, 0)},
+			{CurrencyGroupAbbr: api.GroupCash, CurrencyAbbr: api.EUR, Buy: api.MustValue("67"), Sell: api.MustValue("70")},
+		},
+	}, time.Time{})
+}
+
+func newTestValidator() *Validator {
+	return &Validator{
+		Provider: ProviderVTB,
+		Pairs:    []string{"USD/RUB", "EUR/RUB"},
+		Groups:   []string{api.GroupTele, api.GroupCash},
+		MaxJump:  10,
+	}
+}
+
+func TestValidator_Validate(t *testing.T) {
+	good := testEx("57", "59")
+
+	tests := []struct {
+		Name   string
+		Prev   []Ex
+		Next   []Ex
+		Reason string
+	}{
+		{"first", nil, good, ""},
+		{"same", good, testEx("58", "60"), ""},
+		{"inverted", good, testEx("60", "59"), ReasonInverted},
+		{"zero", good, testEx("0", "59"), ReasonInvalid},
+		{"absurd", good, testEx("57", "1000000000"), ReasonInvalid},
+		{"missing pair", good, good[:1], ReasonMissing},
+		{"jump", good, testEx("70", "72"), ReasonJump},
+		{"duplicate tiers", good, duplicateTiers(), ReasonTiers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			err := newTestValidator().Validate(tt.Prev, tt.Next)
+			if tt.Reason == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			e, ok := err.(*ValidationError)
+			if !ok || e.Reason != tt.Reason {
+				t.Errorf("want %s, got %v", tt.Reason, err)
+			}
+		})
+	}
+}
+
+func TestValidator_Validate_confirmedJump(t *testing.T) {
+	v := newTestValidator()
+	prev := testEx("57", "59")
+
+	if err := v.Validate(prev, testEx("70", "72")); err == nil {
+		t.Fatal("want jump error")
+	}
+	if err := v.Validate(prev, testEx("70.5", "72.5")); err != nil {
+		t.Fatalf("confirmed jump: %v", err)
+	}
+}
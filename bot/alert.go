@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/koorgoo/telegram"
+	"github.com/koorgoo/vtb24/alert"
+	"github.com/koorgoo/vtb24/locale"
+)
+
+// Subscribe adds a subscription of the chat.
+func Subscribe(s *alert.Store) Handler {
+	return func(c *Context) error {
+		sub, err := alert.Parse(c.Args)
+		switch err {
+		case nil:
+		case alert.ErrCurrency:
+			return c.Reply(c.Locale.Sprintf(locale.MsgSubscribeCurrency))
+		case alert.ErrGroup:
+			return c.Reply(c.Locale.Sprintf(locale.MsgSubscribeGroup))
+		default:
+			return c.ReplyWithMode(c.Locale.Sprintf(locale.MsgSubscribeUsage), telegram.ModeMarkdown)
+		}
+		sub.ChatID = c.ChatID
+		switch sub, err = s.Add(sub); err {
+		case nil:
+		case alert.ErrLimit:
+			return c.Reply(c.Locale.Sprintf(locale.MsgSubscribeLimit, alert.MaxSubscriptions))
+		default:
+			_ = c.Reply(c.Locale.Sprintf(locale.MsgSubscribeFailed))
+			return err
+		}
+		return c.Reply(c.Locale.Sprintf(locale.MsgSubscription, sub.ID, sub.Format(c.Locale)))
+	}
+}
+
+// Subscriptions lists subscriptions of the chat.
+func Subscriptions(s *alert.Store) Handler {
+	return func(c *Context) error {
+		v := s.List(c.ChatID)
+		if len(v) == 0 {
+			return c.Reply(c.Locale.Sprintf(locale.MsgNoSubscriptions))
+		}
+		var buf bytes.Buffer
+		fmt.Fprintln(&buf, c.Locale.Plural(locale.MsgSubscriptions, len(v)))
+		for _, sub := range v {
+			fmt.Fprintf(&buf, "%d: %s\n", sub.ID, sub.Format(c.Locale))
+		}
+		return c.Reply(buf.String())
+	}
+}
+
+// Unsubscribe deletes a subscription of the chat.
+func Unsubscribe(s *alert.Store) Handler {
+	return func(c *Context) error {
+		if len(c.Args) != 1 {
+			return c.ReplyWithMode(c.Locale.Sprintf(locale.MsgUnsubscribeUsage), telegram.ModeMarkdown)
+		}
+		id, err := strconv.ParseInt(c.Args[0], 10, 64)
+		if err != nil {
+			return c.ReplyWithMode(c.Locale.Sprintf(locale.MsgUnsubscribeUsage), telegram.ModeMarkdown)
+		}
+		switch err = s.Delete(c.ChatID, id); err {
+		case nil:
+			return c.Reply(c.Locale.Sprintf(locale.MsgSubscriptionDeleted, id))
+		case alert.ErrNotFound:
+			return c.Reply(c.Locale.Sprintf(locale.MsgSubscriptionNotFound, id))
+		default:
+			_ = c.Reply(c.Locale.Sprintf(locale.MsgUnsubscribeFailed))
+			return err
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/koorgoo/telegram"
+)
+
+// DefaultAPIURL is a URL of Telegram Bot API.
+const DefaultAPIURL = "https://api.telegram.org"
+
+// API calls Telegram Bot API methods the telegram package does not support.
+type API struct {
+	Token  string
+	Client *http.Client
+	// URL defaults to DefaultAPIURL.
+	URL string
+}
+
+// AnswerInlineQuery answers an inline query with articles. It is an
+// AnswerFunc.
+func (a *API) AnswerInlineQuery(ctx context.Context, queryID string, results []InlineResult, cacheTime time.Duration) error {
+	type content struct {
+		MessageText string `json:"message_text"`
+		ParseMode   string `json:"parse_mode,omitempty"`
+	}
+	type article struct {
+		Type        string  `json:"type"`
+		ID          string  `json:"id"`
+		Title       string  `json:"title"`
+		Description string  `json:"description,omitempty"`
+		Content     content `json:"input_message_content"`
+	}
+
+	articles := make([]article, len(results))
+	for i, r := range results {
+		articles[i] = article{
+			Type:        "article",
+			ID:          r.ID,
+			Title:       r.Title,
+			Description: r.Description,
+			Content:     content{MessageText: r.Text, ParseMode: string(r.ParseMode)},
+		}
+	}
+	return a.call(ctx, "answerInlineQuery", map[string]interface{}{
+		"inline_query_id": queryID,
+		"results":         articles,
+		"cache_time":      int(cacheTime / time.Second),
+		// Results depend on user settings.
+		"is_personal": true,
+	})
+}
+
+// SendMessage sends a text message. It is a SendFunc.
+func (a *API) SendMessage(ctx context.Context, m *telegram.TextMessage) error {
+	params := map[string]interface{}{
+		"chat_id": m.ChatID,
+		"text":    m.Text,
+	}
+	if m.ParseMode != "" {
+		params["parse_mode"] = m.ParseMode
+	}
+	return a.call(ctx, "sendMessage", params)
+}
+
+// SendKeyboard sends a message with an inline keyboard or edits a sent
+// message when m.MessageID is set. It is a KeyboardFunc.
+func (a *API) SendKeyboard(ctx context.Context, m *KeyboardMessage) error {
+	type button struct {
+		Text         string `json:"text"`
+		CallbackData string `json:"callback_data"`
+	}
+	rows := make([][]button, len(m.Keyboard))
+	for i, row := range m.Keyboard {
+		rows[i] = make([]button, len(row))
+		for j, b := range row {
+			rows[i][j] = button{Text: b.Text, CallbackData: b.Data}
+		}
+	}
+
+	params := map[string]interface{}{
+		"chat_id":      m.ChatID,
+		"text":         m.Text,
+		"reply_markup": map[string]interface{}{"inline_keyboard": rows},
+	}
+	if m.ParseMode != "" {
+		params["parse_mode"] = m.ParseMode
+	}
+	if m.MessageID == 0 {
+		return a.call(ctx, "sendMessage", params)
+	}
+	params["message_id"] = m.MessageID
+	return a.call(ctx, "editMessageText", params)
+}
+
+// AnswerCallbackQuery answers a callback query. Non-empty text is shown as
+// a notification. It is a CallbackAnswerFunc.
+func (a *API) AnswerCallbackQuery(ctx context.Context, queryID, text string) error {
+	params := map[string]interface{}{"callback_query_id": queryID}
+	if text != "" {
+		params["text"] = text
+	}
+	return a.call(ctx, "answerCallbackQuery", params)
+}
+
+// SetWebhook asks Telegram to send updates to hookURL with secret token.
+func (a *API) SetWebhook(ctx context.Context, hookURL, secret string) error {
+	return a.call(ctx, "setWebhook", map[string]interface{}{
+		"url":          hookURL,
+		"secret_token": secret,
+	})
+}
+
+func (a *API) call(ctx context.Context, method string, params interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("bot: %s: %s", method, err)
+	}
+	u := a.URL
+	if u == "" {
+		u = DefaultAPIURL
+	}
+	req, err := http.NewRequest("POST", u+"/bot"+a.Token+"/"+method, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("bot: %s: %s", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		// Do not leak the token in the URL.
+		if e, ok := err.(*url.Error); ok {
+			err = e.Err
+		}
+		return fmt.Errorf("bot: %s: %s", method, err)
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("bot: %s: %s", method, err)
+	}
+	if !r.OK {
+		return fmt.Errorf("bot: %s: %s", method, r.Description)
+	}
+	return nil
+}
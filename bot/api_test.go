@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/koorgoo/telegram"
+)
+
+func TestAPI_AnswerInlineQuery(t *testing.T) {
+	var params struct {
+		InlineQueryID string `json:"inline_query_id"`
+		CacheTime     int    `json:"cache_time"`
+		Results       []struct {
+			Type    string `json:"type"`
+			ID      string `json:"id"`
+			Content struct {
+				MessageText string `json:"message_text"`
+				ParseMode   string `json:"parse_mode"`
+			} `json:"input_message_content"`
+		} `json:"results"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottoken/answerInlineQuery" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer srv.Close()
+
+	a := &API{Token: "token", URL: srv.URL}
+	results := []InlineResult{{ID: "tele", Title: "online", Text: "*1*", ParseMode: telegram.ModeMarkdown}}
+	if err := a.AnswerInlineQuery(context.Background(), "42", results, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if params.InlineQueryID != "42" || params.CacheTime != 60 || len(params.Results) != 1 {
+		t.Fatalf("unexpected params %+v", params)
+	}
+	r := params.Results[0]
+	if r.Type != "article" || r.ID != "tele" || r.Content.MessageText != "*1*" || r.Content.ParseMode != "Markdown" {
+		t.Errorf("unexpected result %+v", r)
+	}
+}
+
+func TestAPI_SendKeyboard(t *testing.T) {
+	var path string
+	var params struct {
+		MessageID   int64 `json:"message_id"`
+		ReplyMarkup struct {
+			InlineKeyboard [][]struct {
+				Text         string `json:"text"`
+				CallbackData string `json:"callback_data"`
+			} `json:"inline_keyboard"`
+		} `json:"reply_markup"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer srv.Close()
+
+	a := &API{Token: "token", URL: srv.URL}
+	m := &KeyboardMessage{ChatID: 1, MessageID: 5, Text: "*1*", Keyboard: Keyboard{{{Text: "USD", Data: "v:1:USD:::"}}}}
+	if err := a.SendKeyboard(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/bottoken/editMessageText" || params.MessageID != 5 {
+		t.Errorf("want message 5 edited, got %s %+v", path, params)
+	}
+	if kb := params.ReplyMarkup.InlineKeyboard; len(kb) != 1 || len(kb[0]) != 1 || kb[0][0].CallbackData != "v:1:USD:::" {
+		t.Errorf("unexpected keyboard %+v", kb)
+	}
+}
+
+func TestAPI_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"description":"Bad Request: query is too old"}`))
+	}))
+	defer srv.Close()
+
+	a := &API{Token: "token", URL: srv.URL}
+	err := a.AnswerInlineQuery(context.Background(), "42", nil, 0)
+	if want := "bot: answerInlineQuery: Bad Request: query is too old"; err == nil || err.Error() != want {
+		t.Errorf("want %q, got %v", want, err)
+	}
+}
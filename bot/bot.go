@@ -0,0 +1,356 @@
+// Package bot dispatches Telegram updates to command handlers.
+package bot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/koorgoo/telegram"
+	"github.com/koorgoo/vtb24/locale"
+)
+
+// SendFunc sends a message to Telegram.
+type SendFunc func(context.Context, *telegram.TextMessage) error
+
+// Handler handles a message.
+type Handler func(*Context) error
+
+// Context describes a message being handled.
+type Context struct {
+	context.Context
+
+	Message *telegram.Message
+	ChatID  int64
+	Text    string
+	// Command is a command name without leading slash and bot name. It is
+	// empty for plain text messages.
+	Command string
+	// Args are space separated words following the command. For plain text
+	// messages Args hold all words of the text.
+	Args []string
+	// Locale formats replies in a language of the chat.
+	Locale *locale.Printer
+
+	send     SendFunc
+	keyboard KeyboardFunc
+	footer   FooterFunc
+}
+
+// Reply sends plain text to the chat of the message.
+func (c *Context) Reply(text string) error {
+	return c.send(c, &telegram.TextMessage{ChatID: c.ChatID, Text: text})
+}
+
+// ReplyWithMode sends text formatted with mode to the chat of the message.
+func (c *Context) ReplyWithMode(text string, mode telegram.ParseMode) error {
+	return c.send(c, &telegram.TextMessage{
+		ChatID:    c.ChatID,
+		Text:      text,
+		ParseMode: mode,
+	})
+}
+
+// ReplyWithKeyboard sends text formatted with mode and an inline keyboard.
+// The keyboard is dropped when the router cannot send keyboards.
+func (c *Context) ReplyWithKeyboard(text string, mode telegram.ParseMode, kb Keyboard) error {
+	if c.keyboard == nil || len(kb) == 0 {
+		return c.ReplyWithMode(text, mode)
+	}
+	return c.keyboard(c, &KeyboardMessage{
+		ChatID:    c.ChatID,
+		Text:      text,
+		ParseMode: mode,
+		Keyboard:  kb,
+	})
+}
+
+// Footer returns a footer to append to replies with rates. It is empty
+// when the router has no FooterFunc.
+func (c *Context) Footer() (string, error) {
+	if c.footer == nil {
+		return "", nil
+	}
+	return c.footer(c)
+}
+
+// InlineResult is an article offered in reply to an inline query.
+type InlineResult struct {
+	ID          string
+	Title       string
+	Description string
+	Text        string
+	ParseMode   telegram.ParseMode
+}
+
+// AnswerFunc answers an inline query. Telegram may cache results for
+// cacheTime.
+type AnswerFunc func(ctx context.Context, queryID string, results []InlineResult, cacheTime time.Duration) error
+
+// InlineHandler handles an inline query.
+type InlineHandler func(*InlineContext) error
+
+// InlineContext describes an inline query being handled.
+type InlineContext struct {
+	context.Context
+
+	Query *telegram.InlineQuery
+	Text  string
+	// Locale formats results in a language of the user.
+	Locale *locale.Printer
+
+	answer AnswerFunc
+}
+
+// Answer answers the query with results cached for cacheTime.
+func (c *InlineContext) Answer(results []InlineResult, cacheTime time.Duration) error {
+	return c.answer(c, c.Query.ID, results, cacheTime)
+}
+
+// Button is an inline keyboard button. Data is sent back in a callback
+// query when the button is pressed.
+type Button struct {
+	Text string
+	Data string
+}
+
+// Keyboard is an inline keyboard of rows of buttons.
+type Keyboard [][]Button
+
+// KeyboardMessage is a text message with an inline keyboard. A message with
+// MessageID replaces the text and the keyboard of a sent message.
+type KeyboardMessage struct {
+	ChatID    int64
+	MessageID int64
+	Text      string
+	ParseMode telegram.ParseMode
+	Keyboard  Keyboard
+}
+
+// KeyboardFunc sends or edits a message with an inline keyboard.
+type KeyboardFunc func(context.Context, *KeyboardMessage) error
+
+// CallbackAnswerFunc answers a callback query with an optional
+// notification text.
+type CallbackAnswerFunc func(ctx context.Context, queryID, text string) error
+
+// CallbackHandler handles a callback query of a pressed button.
+type CallbackHandler func(*CallbackContext) error
+
+// CallbackContext describes a callback query being handled.
+type CallbackContext struct {
+	context.Context
+
+	Query *telegram.CallbackQuery
+	// ChatID and MessageID identify a message with the pressed button.
+	ChatID    int64
+	MessageID int64
+	Data      string
+	// Locale formats texts in a language of the chat.
+	Locale *locale.Printer
+
+	keyboard KeyboardFunc
+	answer   CallbackAnswerFunc
+}
+
+// Edit replaces the text and the keyboard of the message.
+func (c *CallbackContext) Edit(text string, mode telegram.ParseMode, kb Keyboard) error {
+	return c.keyboard(c, &KeyboardMessage{
+		ChatID:    c.ChatID,
+		MessageID: c.MessageID,
+		Text:      text,
+		ParseMode: mode,
+		Keyboard:  kb,
+	})
+}
+
+// Answer answers the query. Telegram shows text as a notification unless
+// text is empty.
+func (c *CallbackContext) Answer(text string) error {
+	return c.answer(c, c.Query.ID, text)
+}
+
+// ObserveFunc is called after a message is handled. command is TextCommand
+// for plain text messages and UnknownCommand for unregistered commands.
+type ObserveFunc func(command string, d time.Duration)
+
+// Command names passed to ObserveFunc.
+const (
+	TextCommand     = "text"
+	UnknownCommand  = "unknown"
+	InlineCommand   = "inline"
+	CallbackCommand = "callback"
+)
+
+// FooterFunc returns a footer of a reply with rates. An empty footer is not
+// shown.
+type FooterFunc func(*Context) (string, error)
+
+// LanguageFunc returns a language of replies to a chat. from is a sender of
+// a message and may be nil.
+type LanguageFunc func(chatID int64, from *telegram.User) string
+
+// UserLanguage returns a supported language of Telegram client of from.
+func UserLanguage(chatID int64, from *telegram.User) string {
+	if from == nil || from.LanguageCode == nil {
+		return ""
+	}
+	return locale.Match(*from.LanguageCode)
+}
+
+// Router routes messages to handlers by command name.
+type Router struct {
+	send     SendFunc
+	handlers map[string]Handler
+	text     Handler
+	unknown  Handler
+	inline   InlineHandler
+	answer   AnswerFunc
+	callback CallbackHandler
+	keyboard KeyboardFunc
+	cbAnswer CallbackAnswerFunc
+	observe  ObserveFunc
+	language LanguageFunc
+	footer   FooterFunc
+}
+
+// NewRouter returns a Router sending replies with send.
+func NewRouter(send SendFunc) *Router {
+	return &Router{send: send, handlers: map[string]Handler{}, language: UserLanguage}
+}
+
+// Handle registers h for a command. The command is given without slash.
+func (r *Router) Handle(command string, h Handler) {
+	r.handlers[command] = h
+}
+
+// HandleText registers h for messages which are not commands.
+func (r *Router) HandleText(h Handler) { r.text = h }
+
+// HandleUnknown registers h for unregistered commands.
+func (r *Router) HandleUnknown(h Handler) { r.unknown = h }
+
+// HandleInline registers h for inline queries answered with answer.
+func (r *Router) HandleInline(answer AnswerFunc, h InlineHandler) {
+	r.answer, r.inline = answer, h
+}
+
+// HandleCallback registers h for callback queries. Messages with keyboards
+// are sent and edited with keyboard, queries are answered with answer.
+func (r *Router) HandleCallback(keyboard KeyboardFunc, answer CallbackAnswerFunc, h CallbackHandler) {
+	r.keyboard, r.cbAnswer, r.callback = keyboard, answer, h
+}
+
+// Observe registers f to be called after each handled message.
+func (r *Router) Observe(f ObserveFunc) { r.observe = f }
+
+// Language registers f to choose languages of replies. Languages default to
+// UserLanguage.
+func (r *Router) Language(f LanguageFunc) { r.language = f }
+
+// Footer registers f to add footers to replies with rates.
+func (r *Router) Footer(f FooterFunc) { r.footer = f }
+
+// HandleUpdate dispatches an update. Updates without text messages, inline
+// or callback queries are ignored.
+func (r *Router) HandleUpdate(ctx context.Context, u *telegram.Update) error {
+	if u.InlineQuery != nil {
+		return r.handleInline(ctx, u.InlineQuery)
+	}
+	if u.CallbackQuery != nil {
+		return r.handleCallback(ctx, u.CallbackQuery)
+	}
+	if u.Message == nil || u.Message.Text == nil {
+		return nil
+	}
+	c := newContext(ctx, u.Message, r.send)
+	c.Locale = locale.New(r.language(c.ChatID, u.Message.From))
+	c.keyboard, c.footer = r.keyboard, r.footer
+
+	var h Handler
+	command := c.Command
+	if command == "" {
+		h, command = r.text, TextCommand
+	} else if h = r.handlers[command]; h == nil {
+		h, command = r.unknown, UnknownCommand
+	}
+	if h == nil {
+		return nil
+	}
+
+	if r.observe != nil {
+		defer func(t time.Time) { r.observe(command, time.Since(t)) }(time.Now())
+	}
+	return h(c)
+}
+
+func (r *Router) handleInline(ctx context.Context, q *telegram.InlineQuery) error {
+	if r.inline == nil {
+		return nil
+	}
+	if r.observe != nil {
+		defer func(t time.Time) { r.observe(InlineCommand, time.Since(t)) }(time.Now())
+	}
+	var lang string
+	if q.From != nil {
+		// Private chats with the bot have ids of users.
+		lang = r.language(q.From.ID, q.From)
+	}
+	return r.inline(&InlineContext{
+		Context: ctx,
+		Query:   q,
+		Text:    strings.TrimSpace(q.Query),
+		Locale:  locale.New(lang),
+		answer:  r.answer,
+	})
+}
+
+func (r *Router) handleCallback(ctx context.Context, q *telegram.CallbackQuery) error {
+	// Buttons of inline messages come without messages.
+	if r.callback == nil || q.Message == nil {
+		return nil
+	}
+	if r.observe != nil {
+		defer func(t time.Time) { r.observe(CallbackCommand, time.Since(t)) }(time.Now())
+	}
+	chatID := q.Message.Chat.ID
+	return r.callback(&CallbackContext{
+		Context:   ctx,
+		Query:     q,
+		ChatID:    chatID,
+		MessageID: q.Message.MessageID,
+		Data:      q.Data,
+		Locale:    locale.New(r.language(chatID, q.From)),
+		keyboard:  r.keyboard,
+		answer:    r.cbAnswer,
+	})
+}
+
+func newContext(ctx context.Context, m *telegram.Message, send SendFunc) *Context {
+	c := &Context{
+		Context: ctx,
+		Message: m,
+		ChatID:  m.Chat.ID,
+		Text:    strings.TrimSpace(*m.Text),
+		send:    send,
+	}
+	c.Command, c.Args = ParseCommand(c.Text)
+	if c.Command == "" {
+		c.Args = strings.Fields(c.Text)
+	}
+	return c
+}
+
+// ParseCommand splits text like "/cmd@bot a b" into command name and
+// arguments. Command is empty when text is not a command.
+func ParseCommand(text string) (command string, args []string) {
+	if !strings.HasPrefix(text, "/") {
+		return "", nil
+	}
+	v := strings.Fields(text)
+	command = strings.TrimPrefix(v[0], "/")
+	if i := strings.Index(command, "@"); i >= 0 {
+		command = command[:i]
+	}
+	return strings.ToLower(command), v[1:]
+}
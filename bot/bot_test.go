@@ -0,0 +1,363 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koorgoo/telegram"
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/donate"
+	"github.com/koorgoo/vtb24/money"
+	"github.com/koorgoo/vtb24/settings"
+)
+
+var ParseCommandTests = []struct {
+	Text    string
+	Command string
+	Args    []string
+}{
+	{"100", "", nil},
+	{"/help", "help", []string{}},
+	{"/Rates@VTB24RatesBot", "rates", []string{}},
+	{"/unsubscribe 1", "unsubscribe", []string{"1"}},
+	{"/subscribe  USD sell < 60", "subscribe", []string{"USD", "sell", "<", "60"}},
+}
+
+func TestParseCommand(t *testing.T) {
+	for _, tt := range ParseCommandTests {
+		t.Run(tt.Text, func(t *testing.T) {
+			command, args := ParseCommand(tt.Text)
+			if command != tt.Command {
+				t.Errorf("command: want %q, got %q", tt.Command, command)
+			}
+			if !reflect.DeepEqual(args, tt.Args) {
+				t.Errorf("args: want %q, got %q", tt.Args, args)
+			}
+		})
+	}
+}
+
+// fakeChat records sent messages.
+type fakeChat struct {
+	sent []*telegram.TextMessage
+}
+
+func (f *fakeChat) send(ctx context.Context, m *telegram.TextMessage) error {
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+func (f *fakeChat) update(text string) *telegram.Update {
+	return &telegram.Update{
+		Message: &telegram.Message{Chat: telegram.Chat{ID: 1}, Text: &text},
+	}
+}
+
+func testRates() *bank.Snapshot {
+	now := time.Now()
+	ex := bank.ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("57"), Sell: api.MustValue("59")},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: "GBP", Buy: api.MustValue("75"), Sell: api.MustValue("78")},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: "JPY", Quantity: money.New(100, 0), Buy: api.MustValue("50"), Sell: api.MustValue("53")},
+			{CurrencyGroupAbbr: api.GroupMetal, CurrencyAbbr: api.XAU, Buy: api.MustValue("2400"), Sell: api.MustValue("2600"), IsMetal: true},
+		},
+	}, now)
+	return &bank.Snapshot{Ex: ex, Time: now}
+}
+
+var RouterTests = []struct {
+	Text  string
+	Reply string
+}{
+	{"/help", "/rates"},
+	{"/nope", "Неизвестная команда"},
+	{"10", "*570*"},
+	{"/rates", "*57*"},
+	{"ten", "только суммы"},
+	{"10 eur", "Не удалось обменять 10 EUR"},
+	{"10 gbp", "*750*"},
+	{"1000 jpy", "*500*"},
+	{"/pairs", "USD/RUB"},
+	{"/metals 10", "*10* г XAU - *24000*"},
+	{"/metals gold", "Формат"},
+	{"/route 10 gbp usd", "1. *12.71* USD\nGBP › RUB _в ВТБ24 - онлайн_\nRUB › USD _в ВТБ24 - онлайн_"},
+	{"/route 10 gbp", "Формат"},
+	{"/pairs gbp eur/usd", "GBP/RUB, EUR/USD"},
+	{"10 gbp в usd", "*10* GBP - *12.71* (покупка) *13.68* (продажа) USD _через RUB_"},
+	{"нужно 100 usd", "_в ВТБ24 - онлайн_: *5900* RUB"},
+	{"нужно 10 usd за gbp", "_в ВТБ24 - онлайн_: *7.87* GBP _через RUB_"},
+	{"/pairs usd/usd", "Формат"},
+	{"/groups cash tele", "Группы: в офисе, наличные, в ВТБ24 - онлайн"},
+	{"/groups tele tele", "Формат: `/groups"},
+	{"/precision 0", "Знаков после запятой: 0"},
+	{"/precision 5", "от 0 до 4"},
+	{"/language EN", "Language: en"},
+	{"/language reset", "Язык: как в Telegram"},
+	{"/language de", "Формат"},
+	{"/settings", "Валютные пары: USD/RUB\nГруппы: в ВТБ24 - онлайн\nЗнаков после запятой: 2\nЯзык: как в Telegram"},
+}
+
+func testPrefs(t *testing.T) *settings.Store {
+	precision := 2
+	prefs, err := settings.Open(filepath.Join(t.TempDir(), "settings.json"), settings.Settings{
+		Pairs:     []string{"USD/RUB"},
+		Groups:    []string{api.GroupTele},
+		Precision: &precision,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prefs
+}
+
+func TestRouter(t *testing.T) {
+	for _, tt := range RouterTests {
+		t.Run(tt.Text, func(t *testing.T) {
+			prefs := testPrefs(t)
+			f := new(fakeChat)
+			r := NewRouter(f.send)
+			r.Handle("help", Help)
+			r.Handle("rates", Rates(testRates, prefs))
+			r.Handle("pairs", Pairs(prefs))
+			r.Handle("groups", Groups(prefs, []string{api.GroupTele, api.GroupCash}))
+			r.Handle("precision", Precision(prefs))
+			r.Handle("language", Language(prefs))
+			r.Handle("settings", Settings(prefs))
+			r.Handle("metals", Metals(testRates, []string{api.GroupMetal}, prefs))
+			r.Handle("route", Route(testRates, prefs))
+			r.HandleText(Amount(testRates, prefs))
+			r.HandleUnknown(Unknown)
+
+			if err := r.HandleUpdate(context.Background(), f.update(tt.Text)); err != nil {
+				t.Fatal(err)
+			}
+			if len(f.sent) != 1 {
+				t.Fatalf("want 1 message, got %d", len(f.sent))
+			}
+			if m := f.sent[0]; m.ChatID != 1 || !strings.Contains(m.Text, tt.Reply) {
+				t.Errorf("want reply to 1 containing %q, got %d: %q", tt.Reply, m.ChatID, m.Text)
+			}
+		})
+	}
+}
+
+func TestRouter_Observe(t *testing.T) {
+	f := new(fakeChat)
+	r := NewRouter(f.send)
+	r.Handle("help", Help)
+	r.HandleText(func(c *Context) error { return nil })
+	r.HandleUnknown(Unknown)
+
+	var commands []string
+	r.Observe(func(command string, d time.Duration) {
+		commands = append(commands, command)
+	})
+
+	for _, text := range []string{"/help", "100", "/nope"} {
+		if err := r.HandleUpdate(context.Background(), f.update(text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"help", TextCommand, UnknownCommand}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("want %q, got %q", want, commands)
+	}
+}
+
+func TestRouter_Language(t *testing.T) {
+	prefs := testPrefs(t)
+	f := new(fakeChat)
+	r := NewRouter(f.send)
+	r.Language(ChatLanguage(prefs))
+	r.Handle("language", Language(prefs))
+	r.HandleText(Amount(testRates, prefs))
+
+	code := "en-US"
+	for _, text := range []string{"10", "/language ru", "10"} {
+		u := f.update(text)
+		u.Message.From = &telegram.User{ID: 1, LanguageCode: &code}
+		if err := r.HandleUpdate(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"(buy)", "Язык: ru", "(покупка)"}
+	if len(f.sent) != len(want) {
+		t.Fatalf("want %d messages, got %+v", len(want), f.sent)
+	}
+	for i, m := range f.sent {
+		if !strings.Contains(m.Text, want[i]) {
+			t.Errorf("want reply containing %q, got %q", want[i], m.Text)
+		}
+	}
+}
+
+func TestAmount_restored(t *testing.T) {
+	restored := func() *bank.Snapshot {
+		s := testRates()
+		s.Time = time.Date(2017, time.October, 1, 9, 30, 0, 0, time.UTC)
+		s.Restored = true
+		return s
+	}
+
+	f := new(fakeChat)
+	r := NewRouter(f.send)
+	r.HandleText(Amount(restored, testPrefs(t)))
+	if err := r.HandleUpdate(context.Background(), f.update("10 usd")); err != nil {
+		t.Fatal(err)
+	}
+	want := "_курсы на 12:30 01.10.2017 МСК_"
+	if len(f.sent) != 1 || !strings.HasSuffix(f.sent[0].Text, want) {
+		t.Errorf("want reply ending with %q, got %+v", want, f.sent)
+	}
+}
+
+func TestInline(t *testing.T) {
+	var answered []InlineResult
+	var cached time.Duration
+	answer := func(ctx context.Context, queryID string, results []InlineResult, cacheTime time.Duration) error {
+		answered, cached = results, cacheTime
+		return nil
+	}
+
+	r := NewRouter(new(fakeChat).send)
+	prefs := testPrefs(t)
+	err := prefs.Update(7, func(s *settings.Settings) { s.Groups = []string{api.GroupTele, api.GroupCash} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.HandleInline(answer, Inline(testRates, prefs, 5*time.Minute))
+	u := &telegram.Update{InlineQuery: &telegram.InlineQuery{ID: "1", From: &telegram.User{ID: 7}, Query: "10 usd"}}
+	if err := r.HandleUpdate(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(answered) != 1 {
+		t.Fatalf("want 1 result, got %+v", answered)
+	}
+	if res := answered[0]; res.ID != api.GroupTele || !strings.Contains(res.Text, "*570*") {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if cached <= 4*time.Minute || cached > 5*time.Minute {
+		t.Errorf("want cache time about 5m, got %v", cached)
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	prefs := testPrefs(t)
+	f := new(fakeChat)
+	r := NewRouter(f.send)
+	r.Handle("precision", Precision(prefs))
+	r.HandleText(Amount(testRates, prefs))
+
+	for _, text := range []string{"/precision 0", "10 gbp в usd"} {
+		if err := r.HandleUpdate(context.Background(), f.update(text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "*10* GBP - *13* (покупка) *14* (продажа) USD"
+	if len(f.sent) != 2 || !strings.Contains(f.sent[1].Text, want) {
+		t.Errorf("want reply containing %q, got %+v", want, f.sent)
+	}
+}
+
+func TestDonateFooter(t *testing.T) {
+	s, err := donate.Open(filepath.Join(t.TempDir(), "donate.json"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := donate.Details{CardNumber: "4276 0000", WishListURL: "https://example.com/wishlist"}
+
+	prefs := testPrefs(t)
+	f := new(fakeChat)
+	r := NewRouter(f.send)
+	r.Footer(DonateFooter(s, d))
+	r.Handle("donate", Donate(s, d))
+	r.HandleText(Amount(testRates, prefs))
+
+	texts := []string{"10", "10", "/donate off", "10", "/donate"}
+	for _, text := range texts {
+		if err := r.HandleUpdate(context.Background(), f.update(text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(f.sent) != len(texts) {
+		t.Fatalf("want %d messages, got %+v", len(texts), f.sent)
+	}
+	// The first footer is shown an interval after the chat is first seen.
+	footers := []bool{false, true, false, false, true}
+	for i, m := range f.sent {
+		if footer := strings.Contains(m.Text, "`4276 0000`"); footer != footers[i] {
+			t.Errorf("%q: want card %v, got %q", texts[i], footers[i], m.Text)
+		}
+	}
+}
+
+func TestBrowse(t *testing.T) {
+	var sent []*KeyboardMessage
+	var answered []string
+	keyboard := func(ctx context.Context, m *KeyboardMessage) error {
+		sent = append(sent, m)
+		return nil
+	}
+	answer := func(ctx context.Context, queryID, text string) error {
+		answered = append(answered, text)
+		return nil
+	}
+
+	prefs := testPrefs(t)
+	r := NewRouter(new(fakeChat).send)
+	r.HandleText(Amount(testRates, prefs))
+	r.HandleCallback(keyboard, answer, Browse(testRates, prefs))
+
+	f := new(fakeChat)
+	if err := r.HandleUpdate(context.Background(), f.update("10 usd")); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || !strings.Contains(sent[0].Text, "*570*") {
+		t.Fatalf("want a message with a keyboard, got %+v", sent)
+	}
+
+	// Press GBP, then sell.
+	for _, b := range []string{"GBP", "продажа"} {
+		data := findButton(sent[len(sent)-1].Keyboard, b)
+		if data == "" {
+			t.Fatalf("no %s button in %+v", b, sent[len(sent)-1].Keyboard)
+		}
+		u := &telegram.Update{CallbackQuery: &telegram.CallbackQuery{
+			ID:      "1",
+			Message: &telegram.Message{MessageID: 9, Chat: telegram.Chat{ID: 1}},
+			Data:    data,
+		}}
+		if err := r.HandleUpdate(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(sent) != 3 || len(answered) != 2 {
+		t.Fatalf("want 2 edits and answers, got %+v, %q", sent, answered)
+	}
+	m := sent[2]
+	if want := "*10* GBP - *780* (продажа) RUB"; m.MessageID != 9 || !strings.Contains(m.Text, want) {
+		t.Errorf("want message 9 edited to contain %q, got %+v", want, m)
+	}
+	if data := findButton(m.Keyboard, "✓ продажа"); data != "v:10:GBP:::" {
+		t.Errorf("want selected sell button deselecting sell, got %q", data)
+	}
+}
+
+func findButton(kb Keyboard, text string) string {
+	for _, row := range kb {
+		for _, b := range row {
+			if b.Text == text {
+				return b.Data
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	"github.com/koorgoo/telegram"
+	"github.com/koorgoo/vtb24/donate"
+	"github.com/koorgoo/vtb24/locale"
+)
+
+// Donate shows ways to donate. Arguments "off" and "on" hide and show
+// donation footers in the chat.
+func Donate(s *donate.Store, d donate.Details) Handler {
+	return func(c *Context) error {
+		if len(c.Args) == 0 {
+			text := c.Locale.Sprintf(locale.MsgDonate) + "\n\n" + formatDetails(d, c.Locale)
+			return c.ReplyWithMode(text, telegram.ModeMarkdown)
+		}
+		var hidden bool
+		switch strings.ToLower(c.Args[0]) {
+		case "off":
+			hidden = true
+		case "on":
+		default:
+			return c.Reply(c.Locale.Sprintf(locale.MsgDonateUsage))
+		}
+		if err := s.SetHidden(c.ChatID, hidden); err != nil {
+			_ = c.Reply(c.Locale.Sprintf(locale.MsgSaveFailed))
+			return err
+		}
+		if hidden {
+			return c.Reply(c.Locale.Sprintf(locale.MsgDonateHidden))
+		}
+		return c.Reply(c.Locale.Sprintf(locale.MsgDonateShown))
+	}
+}
+
+// DonateFooter returns donation footers at most once per interval of s
+// unless the chat hid them.
+func DonateFooter(s *donate.Store, d donate.Details) FooterFunc {
+	return func(c *Context) (string, error) {
+		show, err := s.Footer(c.ChatID, time.Now())
+		if !show {
+			return "", err
+		}
+		p := c.Locale
+		return "\n\n" + p.Sprintf(locale.MsgDonateFooter) + "\n" + formatDetails(d, p) +
+			"\n" + p.Sprintf(locale.MsgDonateOff), err
+	}
+}
+
+func formatDetails(d donate.Details, p *locale.Printer) string {
+	var v []string
+	if d.CardNumber != "" {
+		v = append(v, p.Sprintf(locale.MsgDonateCard, d.CardNumber))
+	}
+	if d.WishListURL != "" {
+		v = append(v, p.Sprintf(locale.MsgDonateWishList, d.WishListURL))
+	}
+	return strings.Join(v, "\n")
+}
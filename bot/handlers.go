@@ -0,0 +1,187 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/koorgoo/telegram"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/chat"
+	"github.com/koorgoo/vtb24/locale"
+	"github.com/koorgoo/vtb24/money"
+	"github.com/koorgoo/vtb24/route"
+	"github.com/koorgoo/vtb24/settings"
+)
+
+// RatesFunc returns current snapshot of exchanges.
+type RatesFunc func() *bank.Snapshot
+
+// Help replies with a list of commands.
+func Help(c *Context) error {
+	return c.ReplyWithMode(c.Locale.Sprintf(locale.MsgHelp), telegram.ModeMarkdown)
+}
+
+// Unknown replies to unregistered commands.
+func Unknown(c *Context) error {
+	return c.Reply(c.Locale.Sprintf(locale.MsgUnknown))
+}
+
+// Amount replies with exchanges of an amount sent as text or, for texts
+// like "нужно 1000 usd", with amounts to pay.
+func Amount(rates RatesFunc, prefs *settings.Store) Handler {
+	return func(c *Context) error {
+		q, err := chat.ParseQuery(c.Text)
+		if err != nil {
+			if q, err = chat.ParseTarget(c.Text); err == nil {
+				return replyTarget(c, q, rates(), prefs.Get(c.ChatID))
+			}
+			return c.Reply(c.Locale.Sprintf(locale.MsgAmountUsage))
+		}
+		return replyView(c, view{Query: q}, rates(), prefs.Get(c.ChatID))
+	}
+}
+
+// Rates replies with exchanges of a currency unit.
+func Rates(rates RatesFunc, prefs *settings.Store) Handler {
+	return func(c *Context) error {
+		return replyView(c, view{Query: chat.Query{Amount: money.New(1, 0)}}, rates(), prefs.Get(c.ChatID))
+	}
+}
+
+// Metals replies with exchanges of precious metals in groups. An optional
+// argument is a weight in grams.
+func Metals(rates RatesFunc, groups []string, prefs *settings.Store) Handler {
+	return func(c *Context) error {
+		q := chat.Query{Amount: money.New(1, 0)}
+		if len(c.Args) > 0 {
+			var err error
+			if q, err = chat.ParseQuery(strings.Join(c.Args, " ")); err != nil {
+				return c.Reply(c.Locale.Sprintf(locale.MsgMetalsUsage))
+			}
+		}
+		s := *rates()
+		s.Ex = bank.FilterEx(s.Ex, bank.WithMetals())
+		// Chat pairs and groups are for currencies.
+		p := prefs.Get(c.ChatID)
+		p.Pairs, p.Groups = nil, groups
+		return replyRates(c, q, &s, p)
+	}
+}
+
+// RoutesLimit is a number of routes shown by Route.
+const RoutesLimit = 5
+
+// Route replies with the most profitable ways to exchange an amount.
+func Route(rates RatesFunc, prefs *settings.Store) Handler {
+	return func(c *Context) error {
+		q, err := chat.ParseQuery(strings.Join(c.Args, " "))
+		if err != nil || q.Src == "" || q.Dst == "" {
+			return c.Reply(c.Locale.Sprintf(locale.MsgRouteUsage))
+		}
+		// Official rates are not available for exchange.
+		ex := bank.FilterEx(rates().Ex, bank.WithProvider(bank.ProviderVTB))
+		routes := route.Find(ex, q.Amount, q.Src, q.Dst, route.DefaultMaxSteps)
+		if len(routes) == 0 {
+			return c.Reply(c.Locale.Sprintf(locale.MsgExchangeFailed, formatQuery(q)))
+		}
+		p := prefs.Get(c.ChatID)
+		p.Language = c.Locale.Lang()
+		return c.ReplyWithMode(chat.MakeRoutesMessage(q, routes, RoutesLimit, p))
+	}
+}
+
+func replyRates(c *Context, q chat.Query, s *bank.Snapshot, p settings.Settings) error {
+	p.Language = c.Locale.Lang()
+	text, mode := makeMessage(q, s, p)
+	return reply(c, q, text, mode, nil)
+}
+
+// replyTarget replies with amounts to pay to get q.Amount of q.Dst.
+func replyTarget(c *Context, q chat.Query, s *bank.Snapshot, p settings.Settings) error {
+	p.Language = c.Locale.Lang()
+	text, mode := chat.MakeTargetMessage(q, s.Ex, p)
+	if text != "" && s.Restored {
+		text += chat.FormatSnapshotTime(s.Time, c.Locale)
+	}
+	return reply(c, chat.Query{Amount: q.Amount, Src: q.Dst}, text, mode, nil)
+}
+
+// replyView replies with rates of v and a keyboard switching views.
+func replyView(c *Context, v view, s *bank.Snapshot, p settings.Settings) error {
+	text, mode, kb := renderView(v, s, p, c.Locale)
+	return reply(c, v.Query, text, mode, kb)
+}
+
+// reply sends rates of q with a footer or tells that q cannot be exchanged
+// when text is empty.
+func reply(c *Context, q chat.Query, text string, mode telegram.ParseMode, kb Keyboard) error {
+	if text == "" {
+		return c.Reply(c.Locale.Sprintf(locale.MsgExchangeFailed, formatQuery(q)))
+	}
+	footer, ferr := c.Footer()
+	if err := c.ReplyWithKeyboard(text+footer, mode, kb); err != nil {
+		return err
+	}
+	return ferr
+}
+
+// Inline answers inline queries with a result per group. Results are cached
+// until the next refresh of rates.
+func Inline(rates RatesFunc, prefs *settings.Store, refresh time.Duration) InlineHandler {
+	return func(c *InlineContext) error {
+		q := chat.Query{Amount: money.New(1, 0)}
+		if c.Text != "" {
+			var err error
+			if q, err = chat.ParseQuery(c.Text); err != nil {
+				return c.Answer(nil, 0)
+			}
+		}
+		// Inline queries come from users, and users' private chats with the
+		// bot have the same ids.
+		p := prefs.Defaults()
+		if c.Query.From != nil {
+			p = prefs.Get(c.Query.From.ID)
+		}
+		p.Language = c.Locale.Lang()
+		s := rates()
+		var results []InlineResult
+		for _, group := range p.Groups {
+			gp := p
+			gp.Groups = []string{group}
+			text, mode := makeMessage(q, s, gp)
+			if text == "" {
+				continue
+			}
+			results = append(results, InlineResult{
+				ID:          group,
+				Title:       c.Locale.Group(group),
+				Description: formatQuery(q),
+				Text:        text,
+				ParseMode:   mode,
+			})
+		}
+		return c.Answer(results, cacheTime(s, refresh))
+	}
+}
+
+// cacheTime returns time left until the next refresh of s.
+func cacheTime(s *bank.Snapshot, refresh time.Duration) time.Duration {
+	if d := refresh - time.Since(s.Time); d > 0 {
+		return d
+	}
+	return 0
+}
+
+func makeMessage(q chat.Query, s *bank.Snapshot, p settings.Settings) (string, telegram.ParseMode) {
+	text, mode := chat.MakeMessage(q, s.Ex, p)
+	// Rates restored on start may be outdated.
+	if text != "" && s.Restored {
+		text += chat.FormatSnapshotTime(s.Time, locale.New(p.Language))
+	}
+	return text, mode
+}
+
+func formatQuery(q chat.Query) string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s", chat.FormatDecimal(q.Amount), q.Src))
+}
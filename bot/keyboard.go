@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/koorgoo/telegram"
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/chat"
+	"github.com/koorgoo/vtb24/locale"
+	"github.com/koorgoo/vtb24/money"
+	"github.com/koorgoo/vtb24/settings"
+)
+
+// Browse switches currencies, groups and sides of messages with rates when
+// their keyboard buttons are pressed. Messages are rendered from current
+// rates.
+func Browse(rates RatesFunc, prefs *settings.Store) CallbackHandler {
+	return func(c *CallbackContext) error {
+		v, ok := parseView(c.Data)
+		if !ok {
+			return c.Answer("")
+		}
+		text, mode, kb := renderView(v, rates(), prefs.Get(c.ChatID), c.Locale)
+		if text == "" {
+			return c.Answer(c.Locale.Sprintf(locale.MsgExchangeFailed, formatQuery(v.Query)))
+		}
+		if err := c.Edit(text, mode, kb); err != nil {
+			_ = c.Answer("")
+			return err
+		}
+		return c.Answer("")
+	}
+}
+
+// view is a state of a message with rates switched by keyboard buttons.
+type view struct {
+	Query chat.Query
+	// Group is a group to show. Empty Group means groups of the chat.
+	Group string
+}
+
+const (
+	viewPrefix = "v"
+	// maxDataLen is a maximal length of callback data allowed by Telegram.
+	maxDataLen = 64
+	// buttonsPerRow is a number of currency buttons in a row.
+	buttonsPerRow = 5
+)
+
+// String returns v as callback data like "v:100:USD::tele:buy".
+func (v view) String() string {
+	q := v.Query
+	return strings.Join([]string{
+		viewPrefix,
+		q.Amount.String(),
+		q.Src,
+		q.Dst,
+		v.Group,
+		string(q.Side),
+	}, ":")
+}
+
+func parseView(data string) (v view, ok bool) {
+	f := strings.Split(data, ":")
+	if len(f) != 6 || f[0] != viewPrefix {
+		return
+	}
+	n, err := money.Parse(f[1])
+	if err != nil || n.Sign() <= 0 {
+		return
+	}
+	side := chat.Side(f[5])
+	if side != "" && side != chat.Buy && side != chat.Sell {
+		return
+	}
+	v.Query = chat.Query{Amount: n, Src: f[2], Dst: f[3], Side: side}
+	v.Group = f[4]
+	return v, true
+}
+
+// renderView returns a message of v with a keyboard switching to other
+// views.
+func renderView(v view, s *bank.Snapshot, p settings.Settings, l *locale.Printer) (string, telegram.ParseMode, Keyboard) {
+	groups := p.Groups
+	p.Language = l.Lang()
+	if v.Group != "" {
+		p.Groups = []string{v.Group}
+	}
+	text, mode := makeMessage(v.Query, s, p)
+	return text, mode, v.keyboard(s.Ex, groups, p.Groups, l)
+}
+
+// keyboard returns rows of currencies available in shown groups, groups of
+// the chat and sides. Pressing a selected button deselects it.
+func (v view) keyboard(ex []bank.Ex, groups, shown []string, p *locale.Printer) Keyboard {
+	var kb Keyboard
+
+	var row []Button
+	for _, code := range chat.Currencies {
+		if code == api.RUB || api.IsMetal(code) || !hasCurrency(ex, code, shown) {
+			continue
+		}
+		next := v
+		if v.Query.Src == code {
+			next.Query.Src = ""
+		} else {
+			next.Query.Src = code
+			if next.Query.Dst == code {
+				next.Query.Dst = ""
+			}
+		}
+		row = append(row, button(code, v.Query.Src == code, next))
+		if len(row) == buttonsPerRow {
+			kb, row = append(kb, row), nil
+		}
+	}
+	if len(row) > 0 {
+		kb = append(kb, row)
+	}
+
+	row = nil
+	for _, group := range groups {
+		next := v
+		if v.Group == group {
+			next.Group = ""
+		} else {
+			next.Group = group
+		}
+		row = append(row, button(p.GroupButton(group), v.Group == group, next))
+	}
+	kb = append(kb, row)
+
+	row = nil
+	for _, side := range []chat.Side{chat.Buy, chat.Sell} {
+		next := v
+		if v.Query.Side == side {
+			next.Query.Side = ""
+		} else {
+			next.Query.Side = side
+		}
+		text := p.Sprintf(locale.MsgBuy)
+		if side == chat.Sell {
+			text = p.Sprintf(locale.MsgSell)
+		}
+		row = append(row, button(text, v.Query.Side == side, next))
+	}
+	kb = append(kb, row)
+
+	for _, row := range kb {
+		for _, b := range row {
+			if len(b.Data) > maxDataLen {
+				return nil
+			}
+		}
+	}
+	return kb
+}
+
+func button(text string, selected bool, next view) Button {
+	if selected {
+		text = "✓ " + text
+	}
+	return Button{Text: text, Data: next.String()}
+}
+
+// hasCurrency reports whether code is exchanged to RUB in groups.
+func hasCurrency(ex []bank.Ex, code string, groups []string) bool {
+	for _, e := range ex {
+		if e.Src() == code && e.Dst() == api.RUB && contains(groups, e.Group()) {
+			return true
+		}
+	}
+	return false
+}
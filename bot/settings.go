@@ -0,0 +1,199 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/koorgoo/telegram"
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/chat"
+	"github.com/koorgoo/vtb24/locale"
+	"github.com/koorgoo/vtb24/settings"
+)
+
+// ChatLanguage returns a language of the chat set with Language or, if it
+// is not set, a language of Telegram client of the sender.
+func ChatLanguage(prefs *settings.Store) LanguageFunc {
+	return func(chatID int64, from *telegram.User) string {
+		if lang := prefs.Get(chatID).Language; lang != "" {
+			return lang
+		}
+		return UserLanguage(chatID, from)
+	}
+}
+
+// Settings shows all settings of the chat or resets them.
+func Settings(prefs *settings.Store) Handler {
+	return func(c *Context) error {
+		switch {
+		case len(c.Args) == 1 && c.Args[0] == "reset":
+			if err := prefs.Reset(c.ChatID); err != nil {
+				_ = c.Reply(c.Locale.Sprintf(locale.MsgSaveFailed))
+				return err
+			}
+		case len(c.Args) > 0:
+			return c.ReplyWithMode(c.Locale.Sprintf(locale.MsgSettingsUsage), telegram.ModeMarkdown)
+		}
+		return c.Reply(formatSettings(prefs.Get(c.ChatID), c.Locale))
+	}
+}
+
+func formatSettings(s settings.Settings, p *locale.Printer) string {
+	return strings.Join([]string{
+		p.Sprintf(locale.MsgPairs, strings.Join(s.Pairs, ", ")),
+		p.Sprintf(locale.MsgGroups, formatGroups(s.Groups, p)),
+		p.Sprintf(locale.MsgPrecision, precision(s)),
+		p.Sprintf(locale.MsgLanguage, formatLanguage(s.Language, p)),
+	}, "\n")
+}
+
+func precision(s settings.Settings) int {
+	if s.Precision != nil {
+		return *s.Precision
+	}
+	return chat.DefaultPrecision
+}
+
+// Pairs shows or changes currency pairs of the chat.
+func Pairs(prefs *settings.Store) Handler {
+	return func(c *Context) error {
+		switch {
+		case len(c.Args) == 0:
+			pairs := prefs.Get(c.ChatID).Pairs
+			return c.Reply(c.Locale.Sprintf(locale.MsgPairs, strings.Join(pairs, ", ")))
+		case len(c.Args) == 1 && c.Args[0] == "reset":
+			err := update(c, prefs, func(s *settings.Settings) { s.Pairs = nil })
+			if err != nil {
+				return err
+			}
+			pairs := prefs.Defaults().Pairs
+			return c.Reply(c.Locale.Sprintf(locale.MsgPairs, strings.Join(pairs, ", ")))
+		}
+
+		var pairs []string
+		for _, arg := range c.Args {
+			src, dst, err := api.ParsePair(arg)
+			if err != nil {
+				return c.ReplyWithMode(c.Locale.Sprintf(locale.MsgPairsUsage), telegram.ModeMarkdown)
+			}
+			pairs = append(pairs, src+"/"+dst)
+		}
+		if err := update(c, prefs, func(s *settings.Settings) { s.Pairs = pairs }); err != nil {
+			return err
+		}
+		return c.Reply(c.Locale.Sprintf(locale.MsgPairs, strings.Join(pairs, ", ")))
+	}
+}
+
+// Groups shows or changes currency groups of the chat and their order.
+// Only available groups may be chosen.
+func Groups(prefs *settings.Store, available []string) Handler {
+	usage := func(p *locale.Printer) string {
+		s := p.Sprintf(locale.MsgGroupsUsage)
+		for _, group := range available {
+			s += fmt.Sprintf("\n`%s` - %s", group, p.Group(group))
+		}
+		return s
+	}
+
+	return func(c *Context) error {
+		switch {
+		case len(c.Args) == 0:
+			return c.Reply(c.Locale.Sprintf(locale.MsgGroups, formatGroups(prefs.Get(c.ChatID).Groups, c.Locale)))
+		case len(c.Args) == 1 && c.Args[0] == "reset":
+			if err := update(c, prefs, func(s *settings.Settings) { s.Groups = nil }); err != nil {
+				return err
+			}
+			return c.Reply(c.Locale.Sprintf(locale.MsgGroups, formatGroups(prefs.Defaults().Groups, c.Locale)))
+		}
+
+		var groups []string
+		for _, arg := range c.Args {
+			group := strings.ToLower(arg)
+			if !contains(available, group) || contains(groups, group) {
+				return c.ReplyWithMode(usage(c.Locale), telegram.ModeMarkdown)
+			}
+			groups = append(groups, group)
+		}
+		if err := update(c, prefs, func(s *settings.Settings) { s.Groups = groups }); err != nil {
+			return err
+		}
+		return c.Reply(c.Locale.Sprintf(locale.MsgGroups, formatGroups(groups, c.Locale)))
+	}
+}
+
+func formatGroups(groups []string, p *locale.Printer) string {
+	v := make([]string, len(groups))
+	for i, group := range groups {
+		v[i] = p.Group(group)
+	}
+	return strings.Join(v, ", ")
+}
+
+// Precision shows or changes a number of decimals of amounts.
+func Precision(prefs *settings.Store) Handler {
+	return func(c *Context) error {
+		if len(c.Args) == 0 {
+			return c.Reply(c.Locale.Sprintf(locale.MsgPrecision, precision(prefs.Get(c.ChatID))))
+		}
+		n, err := strconv.Atoi(c.Args[0])
+		if err != nil || len(c.Args) > 1 || n < 0 || n > chat.MaxPrecision {
+			return c.Reply(c.Locale.Sprintf(locale.MsgPrecisionUsage, chat.MaxPrecision))
+		}
+		if err = update(c, prefs, func(s *settings.Settings) { s.Precision = &n }); err != nil {
+			return err
+		}
+		return c.Reply(c.Locale.Sprintf(locale.MsgPrecision, n))
+	}
+}
+
+// Language shows or changes a language of replies. Reset makes replies
+// follow languages of Telegram clients.
+func Language(prefs *settings.Store) Handler {
+	return func(c *Context) error {
+		if len(c.Args) == 0 {
+			return c.Reply(c.Locale.Sprintf(locale.MsgLanguage, formatLanguage(prefs.Get(c.ChatID).Language, c.Locale)))
+		}
+		lang := strings.ToLower(c.Args[0])
+		if lang == "reset" {
+			lang = ""
+		}
+		if len(c.Args) > 1 || lang != "" && !contains(locale.Languages, lang) {
+			return c.Reply(c.Locale.Sprintf(locale.MsgLanguageUsage, strings.Join(locale.Languages, ", ")))
+		}
+		if err := update(c, prefs, func(s *settings.Settings) { s.Language = lang }); err != nil {
+			return err
+		}
+		// Reply in the chosen language.
+		if lang != "" {
+			c.Locale = locale.New(lang)
+		}
+		return c.Reply(c.Locale.Sprintf(locale.MsgLanguage, formatLanguage(lang, c.Locale)))
+	}
+}
+
+func formatLanguage(lang string, p *locale.Printer) string {
+	if lang == "" {
+		return p.Sprintf(locale.MsgLanguageAuto)
+	}
+	return lang
+}
+
+// update changes settings of the chat and replies on failure.
+func update(c *Context, prefs *settings.Store, f func(*settings.Settings)) error {
+	if err := prefs.Update(c.ChatID, f); err != nil {
+		_ = c.Reply(c.Locale.Sprintf(locale.MsgSaveFailed))
+		return err
+	}
+	return nil
+}
+
+func contains(v []string, s string) bool {
+	for i := range v {
+		if v[i] == s {
+			return true
+		}
+	}
+	return false
+}
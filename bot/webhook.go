@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/koorgoo/telegram"
+)
+
+// SecretHeader is a header Telegram sends a webhook secret token in.
+const SecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// maxUpdateSize limits a size of an update body.
+const maxUpdateSize = 1 << 20
+
+// Webhook is an http.Handler receiving updates sent by Telegram.
+type Webhook struct {
+	secret  string
+	updatec chan *telegram.Update
+}
+
+// NewWebhook returns a Webhook accepting requests with secret token.
+func NewWebhook(secret string) *Webhook {
+	return &Webhook{secret: secret, updatec: make(chan *telegram.Update, 100)}
+}
+
+// Updates returns a channel of received updates.
+func (w *Webhook) Updates() <-chan *telegram.Update { return w.updatec }
+
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		rw.Header().Set("Allow", "POST")
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := r.Header.Get(SecretHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(w.secret)) != 1 {
+		http.Error(rw, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var u telegram.Update
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxUpdateSize)).Decode(&u); err != nil {
+		http.Error(rw, "invalid update", http.StatusBadRequest)
+		return
+	}
+
+	// Telegram resends an update until it is accepted.
+	select {
+	case w.updatec <- &u:
+	case <-r.Context().Done():
+		http.Error(rw, "busy", http.StatusServiceUnavailable)
+	}
+}
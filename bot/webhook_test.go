@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var WebhookTests = []struct {
+	Name   string
+	Method string
+	Secret string
+	Body   string
+	Status int
+}{
+	{"ok", "POST", "secret", `{"update_id":1,"message":{"chat":{"id":1},"text":"/help"}}`, http.StatusOK},
+	{"get", "GET", "secret", "", http.StatusMethodNotAllowed},
+	{"no secret", "POST", "", `{"update_id":1}`, http.StatusForbidden},
+	{"wrong secret", "POST", "wrong", `{"update_id":1}`, http.StatusForbidden},
+	{"not json", "POST", "secret", "update", http.StatusBadRequest},
+}
+
+func TestWebhook(t *testing.T) {
+	for _, tt := range WebhookTests {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := NewWebhook("secret")
+			req := httptest.NewRequest(tt.Method, "/telegram", strings.NewReader(tt.Body))
+			if tt.Secret != "" {
+				req.Header.Set(SecretHeader, tt.Secret)
+			}
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, req)
+
+			if rec.Code != tt.Status {
+				t.Errorf("want status %d, got %d", tt.Status, rec.Code)
+			}
+			if n := len(w.Updates()); (n == 1) != (tt.Status == http.StatusOK) {
+				t.Errorf("unexpected %d updates", n)
+			}
+		})
+	}
+}
+
+// TestWebhook_pipeline sends an update through a webhook and a router and
+// expects a reply sent to a fake Telegram.
+func TestWebhook_pipeline(t *testing.T) {
+	sent := make(chan map[string]interface{}, 1)
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottoken/sendMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var params map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Error(err)
+		}
+		sent <- params
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer tg.Close()
+
+	wh := NewWebhook("secret")
+	srv := httptest.NewServer(wh)
+	defer srv.Close()
+
+	a := &API{Token: "token", URL: tg.URL}
+	r := NewRouter(a.SendMessage)
+	r.Handle("help", Help)
+
+	req, err := http.NewRequest("POST", srv.URL, strings.NewReader(`{"update_id":1,"message":{"chat":{"id":7},"text":"/help"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(SecretHeader, "secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if err := r.HandleUpdate(context.Background(), <-wh.Updates()); err != nil {
+		t.Fatal(err)
+	}
+	params := <-sent
+	if params["chat_id"] != float64(7) || !strings.Contains(params["text"].(string), "/rates") || params["parse_mode"] != "Markdown" {
+		t.Errorf("unexpected message %v", params)
+	}
+}
@@ -0,0 +1,167 @@
+// Package cbr provides official exchange rates of the Central Bank of Russia.
+package cbr
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/exchange"
+	"github.com/koorgoo/vtb24/money"
+)
+
+const (
+	// ProviderName is a name of the provider.
+	ProviderName = "cbr"
+	// DailyURL returns daily rates in XML.
+	DailyURL = "https://www.cbr.ru/scripts/XML_daily.asp"
+)
+
+// Provider is a bank.Provider of official CBR rates. Rates are quoted in
+// api.GroupCBR group.
+type Provider struct {
+	Client *http.Client
+	// URL defaults to DailyURL.
+	URL string
+	// MaxBodySize limits a response body. Zero means api.DefaultMaxBodySize.
+	MaxBodySize int64
+
+	last *ValCurs
+}
+
+var ErrBodyTooLarge = errors.New("cbr: response body too large")
+
+var _ bank.Provider = (*Provider)(nil)
+
+func (p *Provider) Name() string { return ProviderName }
+
+func (p *Provider) Ex(ctx context.Context) ([]bank.Ex, error) {
+	url := p.URL
+	if url == "" {
+		url = DailyURL
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("cbr: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cbr: unexpected status %s", resp.Status)
+	}
+
+	maxBodySize := p.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = api.DefaultMaxBodySize
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cbr: %s", err)
+	}
+	if int64(len(b)) > maxBodySize {
+		return nil, ErrBodyTooLarge
+	}
+	doc, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	ex, err := doc.Ex(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	p.last = doc
+	return ex, nil
+}
+
+// Last returns a document fetched by the last successful call of Ex.
+func (p *Provider) Last() *ValCurs { return p.last }
+
+// dateLayout is a layout of ValCurs.Date.
+const dateLayout = "02.01.2006"
+
+// ValCurs is a daily rates document.
+type ValCurs struct {
+	Date   string   `xml:"Date,attr" json:"date"`
+	Valute []Valute `xml:"Valute" json:"valute"`
+}
+
+// Valute is an official rate of Nominal units of a currency in RUB.
+type Valute struct {
+	CharCode string `xml:"CharCode" json:"char_code"`
+	Nominal  int    `xml:"Nominal" json:"nominal"`
+	Name     string `xml:"Name" json:"name"`
+	Value    string `xml:"Value" json:"value"`
+}
+
+// Rate returns a rate of a currency unit in RUB.
+func (v *Valute) Rate() (money.Decimal, error) {
+	d, err := money.Parse(strings.Replace(v.Value, ",", ".", 1))
+	if err != nil {
+		return money.Decimal{}, err
+	}
+	if v.Nominal > 0 {
+		d = d.Div(money.New(int64(v.Nominal), 0))
+	}
+	return d, nil
+}
+
+func newDecoder(r io.Reader) *xml.Decoder {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charsetReader
+	return dec
+}
+
+// Parse parses daily rates XML fetched at fetched time into exchanges to
+// RUB.
+func Parse(r io.Reader, fetched time.Time) ([]bank.Ex, error) {
+	doc, err := Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Ex(fetched)
+}
+
+// Decode decodes daily rates XML.
+func Decode(r io.Reader) (*ValCurs, error) {
+	var doc ValCurs
+	if err := newDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("cbr: %s", err)
+	}
+	return &doc, nil
+}
+
+// Ex returns exchanges to RUB of doc fetched at fetched time. Official
+// rates have equal buy and sell and are active from the beginning of the
+// document date in Moscow.
+func (doc *ValCurs) Ex(fetched time.Time) ([]bank.Ex, error) {
+	active, err := time.ParseInLocation(dateLayout, doc.Date, api.Moscow)
+	if err != nil {
+		return nil, fmt.Errorf("cbr: %s", err)
+	}
+
+	v := make([]bank.Ex, 0, len(doc.Valute))
+	for _, val := range doc.Valute {
+		rate, err := val.Rate()
+		if err != nil {
+			return nil, fmt.Errorf("cbr: %s: %s", val.CharCode, err)
+		}
+		e := exchange.New(exchange.NewRate(rate, rate, nil))
+		v = append(v, bank.NewEx(val.CharCode, api.RUB, api.GroupCBR, ProviderName, active, fetched, e))
+	}
+	return v, nil
+}
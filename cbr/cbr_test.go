@@ -0,0 +1,82 @@
+package cbr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+)
+
+func TestProvider_Ex(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/XML_daily.xml")
+	}))
+	defer srv.Close()
+
+	p := &Provider{URL: srv.URL}
+	ex, err := p.Ex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Src  string
+		Rate float64
+	}{
+		{api.USD, 57.8383},
+		{api.EUR, 68.5162},
+		{api.JPY, 0.515217},
+	}
+	if len(ex) != len(tests) {
+		t.Fatalf("want %d rates, got %d", len(tests), len(ex))
+	}
+	active := time.Date(2017, 10, 13, 0, 0, 0, 0, api.Moscow)
+	for i, tt := range tests {
+		e := ex[i]
+		if !e.ActiveFrom().Equal(active) {
+			t.Errorf("%s: want active from %v, got %v", tt.Src, active, e.ActiveFrom())
+		}
+		if e.Src() != tt.Src || e.Dst() != api.RUB || e.Group() != api.GroupCBR || e.Provider() != ProviderName {
+			t.Errorf("%d: unexpected %s/%s %s %s", i, e.Src(), e.Dst(), e.Group(), e.Provider())
+		}
+		if n, _ := e.Buy(1); n != tt.Rate {
+			t.Errorf("%s: want %v, got %v", tt.Src, tt.Rate, n)
+		}
+	}
+	if doc := p.Last(); doc == nil || len(doc.Valute) != len(tests) {
+		t.Errorf("want the last document, got %+v", doc)
+	}
+}
+
+func TestProvider_Ex_tooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/XML_daily.xml")
+	}))
+	defer srv.Close()
+
+	p := &Provider{URL: srv.URL, MaxBodySize: 100}
+	if _, err := p.Ex(context.Background()); err != ErrBodyTooLarge {
+		t.Errorf("want %v, got %v", ErrBodyTooLarge, err)
+	}
+}
+
+func TestParse_names(t *testing.T) {
+	f, err := os.Open("testdata/XML_daily.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var doc ValCurs
+	dec := newDecoder(f)
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if name := doc.Valute[0].Name; name != "Доллар США" {
+		t.Errorf("want %q, got %q", "Доллар США", name)
+	}
+}
@@ -0,0 +1,68 @@
+package cbr
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+func charsetReader(charset string, r io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "windows-1251", "cp1251":
+		return &cp1251Reader{r: bufio.NewReader(r)}, nil
+	case "utf-8", "":
+		return r, nil
+	default:
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+}
+
+// cp1251Reader converts windows-1251 text to UTF-8.
+type cp1251Reader struct {
+	r   *bufio.Reader
+	buf bytes.Buffer
+}
+
+func (c *cp1251Reader) Read(p []byte) (int, error) {
+	for c.buf.Len() < len(p) {
+		b, err := c.r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		c.buf.WriteRune(decodeCP1251(b))
+	}
+	if c.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.buf.Read(p)
+}
+
+func decodeCP1251(b byte) rune {
+	switch {
+	case b < 0x80:
+		return rune(b)
+	case b >= 0xC0:
+		// А..я
+		return rune(b-0xC0) + 0x0410
+	default:
+		return cp1251[b-0x80]
+	}
+}
+
+// cp1251 maps bytes 0x80..0xBF.
+var cp1251 = [64]rune{
+	0x0402, 0x0403, 0x201A, 0x0453, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x20AC, 0x2030, 0x0409, 0x2039, 0x040A, 0x040C, 0x040B, 0x040F,
+	0x0452, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	utf8.RuneError, 0x2122, 0x0459, 0x203A, 0x045A, 0x045C, 0x045B, 0x045F,
+	0x00A0, 0x040E, 0x045E, 0x0408, 0x00A4, 0x0490, 0x00A6, 0x00A7,
+	0x0401, 0x00A9, 0x0404, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x0407,
+	0x00B0, 0x00B1, 0x0406, 0x0456, 0x0491, 0x00B5, 0x00B6, 0x00B7,
+	0x0451, 0x2116, 0x0454, 0x00BB, 0x0458, 0x0405, 0x0455, 0x0457,
+}
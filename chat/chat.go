@@ -3,17 +3,36 @@ package chat
 import (
 	"bytes"
 	"fmt"
-	"math/big"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/koorgoo/telegram"
 	"github.com/koorgoo/vtb24/api"
 	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/locale"
+	"github.com/koorgoo/vtb24/money"
+	"github.com/koorgoo/vtb24/settings"
 )
 
-func MakeMessage(n float64, ex []bank.Ex, groups []string) (text string, mode telegram.ParseMode) {
+var now = time.Now
+
+// MakeMessage returns exchanges of q formatted with chat preferences. Only
+// prefs.Pairs are shown unless q requests currencies explicitly.
+func MakeMessage(q Query, ex []bank.Ex, prefs settings.Settings) (text string, mode telegram.ParseMode) {
+	if q.Src == "" && q.Dst == "" && len(prefs.Pairs) > 0 {
+		ex = bank.FilterEx(withCrossPairs(ex, prefs.Pairs), bank.WithPairs(prefs.Pairs...))
+	}
+	f := newFormatter(prefs)
+
+	// Offer a rate through RUB next to a direct cross rate.
+	if q.Src != "" && q.Dst != "" && q.Src != api.RUB && q.Dst != api.RUB {
+		ex = append(ex[:len(ex):len(ex)], bank.CrossEx(ex, q.Src, q.Dst, api.RUB)...)
+	}
+
 	m := map[string][]bank.Ex{}
 	for _, e := range ex {
 		m[e.Group()] = append(m[e.Group()], e)
@@ -21,68 +40,314 @@ func MakeMessage(n float64, ex []bank.Ex, groups []string) (text string, mode te
 
 	var buf bytes.Buffer
 	var hasGroups = true
+	var active, fetched time.Time
 
-	for _, group := range groups {
+	for _, group := range prefs.Groups {
 		var writeGroup sync.Once
 
 		for _, e := range m[group] {
-			s, ok := formatOp(n, e)
-			si, oki := formatOp(n, bank.Invert(e))
+			s, ok := f.op(q, e)
+			si, oki := f.op(q, bank.Invert(e))
 
 			if !ok && !oki {
 				continue
 			}
 
 			writeGroup.Do(func() {
-				fmt.Fprintf(&buf, formatGroup(e.Group(), !hasGroups))
+				buf.WriteString(f.group(e.Group(), !hasGroups))
 				hasGroups = true
 			})
 
 			if ok {
 				fmt.Fprintln(&buf, s)
+				f.writeTiers(&buf, q, e)
 			}
 			if oki {
 				fmt.Fprintln(&buf, si)
 			}
+			if e.ActiveFrom().After(active) {
+				active = e.ActiveFrom()
+			}
+			if fetched.IsZero() || e.FetchedAt().Before(fetched) {
+				fetched = e.FetchedAt()
+			}
 
 			// Line break between ops.
 			fmt.Fprintln(&buf)
 		}
 	}
+
+	if buf.Len() > 0 {
+		f.writeOfficial(&buf, q, m, prefs.Groups)
+		f.writeFreshness(&buf, active, fetched)
+	}
 	return buf.String(), telegram.ModeMarkdown
 }
 
-func formatOp(n float64, e bank.Ex) (s string, ok bool) {
-	buy, err := e.Buy(n)
+// formatter formats messages with chat preferences.
+type formatter struct {
+	p      *locale.Printer
+	prec   int
+	maxAge time.Duration
+}
+
+func newFormatter(prefs settings.Settings) *formatter {
+	f := &formatter{p: locale.New(prefs.Language), prec: DefaultPrecision, maxAge: prefs.MaxAge}
+	if prefs.Precision != nil {
+		f.prec = *prefs.Precision
+	}
+	return f
+}
+
+// writeFreshness writes time rates are active from and warns when rates
+// were fetched more than maxAge ago.
+func (f *formatter) writeFreshness(buf *bytes.Buffer, active, fetched time.Time) {
+	if !active.IsZero() {
+		fmt.Fprintln(buf, f.p.Sprintf(locale.MsgActiveFrom, formatTime(active, f.p)))
+	}
+	if d := now().Sub(fetched); f.maxAge > 0 && !fetched.IsZero() && d > f.maxAge {
+		fmt.Fprintln(buf, f.p.Sprintf(locale.MsgStale, formatAge(d, f.p)))
+	}
+}
+
+// formatAge returns d in minutes or, for a few hours, in hours.
+func formatAge(d time.Duration, p *locale.Printer) string {
+	if d < 2*time.Hour {
+		return p.Plural(locale.MsgMinutes, int(d/time.Minute))
+	}
+	return p.Plural(locale.MsgHours, int(d/time.Hour))
+}
+
+// writeOfficial writes official CBR rates and markups of groups over them.
+func (f *formatter) writeOfficial(buf *bytes.Buffer, q Query, m map[string][]bank.Ex, groups []string) {
+	var writeGroup sync.Once
+
+	for _, o := range m[api.GroupCBR] {
+		s, ok := f.official(q, o)
+		si, oki := f.official(q, bank.Invert(o))
+		if !ok && !oki {
+			continue
+		}
+
+		writeGroup.Do(func() {
+			buf.WriteString(f.group(api.GroupCBR, false))
+		})
+		if ok {
+			fmt.Fprintln(buf, s)
+		}
+		if oki {
+			fmt.Fprintln(buf, si)
+		}
+
+		for _, group := range groups {
+			for _, e := range m[group] {
+				if e.Src() != o.Src() || e.Dst() != o.Dst() {
+					continue
+				}
+				if _, ok := bank.Via(e); ok {
+					continue
+				}
+				buy, sell, ok := bank.Markup(e, o)
+				if !ok {
+					continue
+				}
+				fmt.Fprintln(buf, f.p.Sprintf(locale.MsgMarkup,
+					f.p.Group(group), formatPercent(buy), formatPercent(sell)))
+			}
+		}
+		fmt.Fprintln(buf)
+	}
+}
+
+func (f *formatter) official(q Query, e bank.Ex) (s string, ok bool) {
+	if !q.match(e) {
+		return
+	}
+	n := q.Amount
+	v, err := e.BuyDecimal(n)
 	if err != nil {
 		return
 	}
-	sell, err := e.Sell(n)
+	s = fmt.Sprintf("*%v* %v - *%v* %v", f.amount(n, e.Src()), e.Src(), f.amount(v, e.Dst()), e.Dst())
+	return s, true
+}
+
+// change returns an arrow of a rate change and a delta for n units.
+func (f *formatter) change(c bank.Change, n money.Decimal) string {
+	var arrow string
+	switch c.Direction {
+	case bank.Up:
+		arrow = "▲"
+	case bank.Down:
+		arrow = "▼"
+	default:
+		return ""
+	}
+	if c.Delta == 0 {
+		return " " + arrow
+	}
+	delta := money.FromFloat(math.Abs(c.Delta)).Mul(n)
+	return fmt.Sprintf(" %s%s (%s%%)", arrow, f.value(delta), formatPercent(math.Abs(c.Percent)))
+}
+
+func formatPercent(v float64) string {
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
+
+func (f *formatter) op(q Query, e bank.Ex) (s string, ok bool) {
+	if !q.match(e) {
+		return
+	}
+	n := q.Amount
+	buy, err := e.BuyDecimal(n)
+	if err != nil {
+		return
+	}
+	sell, err := e.SellDecimal(n)
 	if err != nil {
 		return
 	}
-	s = fmt.Sprintf("*%v* %v - *%v* (покупка) *%v* (продажа) %v",
-		n, e.Src(), FormatValue(buy), FormatValue(sell), e.Dst())
+	src, dst := e.Src(), e.Dst()
+	cb, cs := e.Change()
+	switch q.Side {
+	case Buy:
+		s = f.p.Sprintf(locale.MsgOpSide,
+			f.amount(n, src), f.unit(e, src),
+			f.amount(buy, dst), f.change(cb, q.Amount), f.p.Sprintf(locale.MsgBuy),
+			f.unit(e, dst))
+	case Sell:
+		s = f.p.Sprintf(locale.MsgOpSide,
+			f.amount(n, src), f.unit(e, src),
+			f.amount(sell, dst), f.change(cs, q.Amount), f.p.Sprintf(locale.MsgSell),
+			f.unit(e, dst))
+	default:
+		s = f.p.Sprintf(locale.MsgOp,
+			f.amount(n, src), f.unit(e, src),
+			f.amount(buy, dst), f.change(cb, q.Amount),
+			f.amount(sell, dst), f.change(cs, q.Amount),
+			f.unit(e, dst))
+	}
+	if via, ok := bank.Via(e); ok {
+		s += " " + f.p.Sprintf(locale.MsgVia, via)
+	}
 	return s, true
 }
 
-func FormatValue(v float64) (s string) {
-	if n := int64(v); float64(n) == v {
-		s = strconv.FormatInt(n, 10)
-	} else {
-		s = big.NewFloat(v).Text('f', 2)
+func (f *formatter) value(d money.Decimal) string { return formatDecimal(d, f.prec) }
+
+// amount formats an amount of a currency with no more decimals than its
+// minor units.
+func (f *formatter) amount(d money.Decimal, code string) string {
+	prec := f.prec
+	if n := money.MinorUnits(code); n < prec {
+		prec = n
 	}
-	if strings.HasSuffix(s, ".00") {
-		s = s[:len(s)-3]
+	return formatDecimal(d, prec)
+}
+
+// unit returns code with a unit of measure. Metals are measured in grams.
+func (f *formatter) unit(e bank.Ex, code string) string {
+	if api.IsMetal(code) || e.IsMetal() && code != api.RUB {
+		return f.p.Sprintf(locale.MsgGram) + " " + code
 	}
-	return
+	return code
 }
 
-func formatGroup(group string, isFirst bool) string {
+// writeTiers writes rates of e for amounts above q.Amount, so that users
+// know when a bigger exchange pays off. Only tiers quoted by a bank are
+// written, i.e. not ones of inverted exchanges or crosses.
+func (f *formatter) writeTiers(buf *bytes.Buffer, q Query, e bank.Ex) {
+	if _, ok := bank.Via(e); ok {
+		return
+	}
+	buy, sell := e.Tiers()
+	amount := q.Amount
+	var thresholds []money.Decimal
+	for _, t := range append(buy[:len(buy):len(buy)], sell...) {
+		if t.From.Cmp(amount) > 0 {
+			thresholds = append(thresholds, t.From)
+		}
+	}
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i].Cmp(thresholds[j]) < 0 })
+
+	for i, x := range thresholds {
+		if i > 0 && thresholds[i-1].Cmp(x) == 0 {
+			continue
+		}
+		b, okb := buy.Find(x)
+		s, oks := sell.Find(x)
+		from, unit := f.amount(x, e.Src()), f.unit(e, e.Src())
+		switch {
+		case q.Side == Buy && okb:
+			fmt.Fprintln(buf, f.p.Sprintf(locale.MsgTierSide, from, unit, f.rate(b.Rate), f.p.Sprintf(locale.MsgBuy)))
+		case q.Side == Sell && oks:
+			fmt.Fprintln(buf, f.p.Sprintf(locale.MsgTierSide, from, unit, f.rate(s.Rate), f.p.Sprintf(locale.MsgSell)))
+		case q.Side == "" && okb && oks:
+			fmt.Fprintln(buf, f.p.Sprintf(locale.MsgTier, from, unit, f.rate(b.Rate), f.rate(s.Rate)))
+		}
+	}
+}
+
+func (f *formatter) rate(d money.Decimal) string { return formatDecimal(d, f.prec) }
+
+func (f *formatter) group(group string, isFirst bool) string {
 	var suffix string
 	if !isFirst {
 		suffix = "\n"
 	}
-	text := api.GroupText(group)
-	return fmt.Sprintf("%s_%s_\n\n", suffix, text)
+	return fmt.Sprintf("%s_%s_\n\n", suffix, f.p.Group(group))
+}
+
+// FormatSnapshotTime returns a note about time rates were fetched at.
+func FormatSnapshotTime(t time.Time, p *locale.Printer) string {
+	return p.Sprintf(locale.MsgSnapshotTime, formatTime(t, p))
+}
+
+func formatTime(t time.Time, p *locale.Printer) string {
+	return t.In(api.Moscow).Format("15:04 02.01.2006") + " " + p.Sprintf(locale.MsgTimeZone)
+}
+
+// withCrossPairs adds rates through RUB for cross pairs like EUR/USD.
+func withCrossPairs(ex []bank.Ex, pairs []string) []bank.Ex {
+	for _, pair := range pairs {
+		src, dst, err := api.ParsePair(pair)
+		if err != nil || dst == api.RUB {
+			continue
+		}
+		ex = append(ex[:len(ex):len(ex)], bank.CrossEx(ex, src, dst, api.RUB)...)
+	}
+	return ex
+}
+
+// DefaultPrecision is a number of decimals of values.
+const DefaultPrecision = 2
+
+// MaxPrecision is the maximal number of decimals of values.
+const MaxPrecision = 4
+
+// Rounding rounds shown amounts.
+const Rounding = money.HalfEven
+
+// FormatDecimal formats d with DefaultPrecision.
+func FormatDecimal(d money.Decimal) string { return formatDecimal(d, DefaultPrecision) }
+
+func formatDecimal(d money.Decimal, prec int) (s string) {
+	switch {
+	case d.IsInt():
+		s = d.String()
+	case d.Sign() > 0 && d.Cmp(money.New(1, 0)) < 0:
+		// Rates of cross pairs may be small, e.g. RUB/USD.
+		s = d.Round(MaxPrecision, Rounding).Text(MaxPrecision)
+		s = strings.TrimRight(s, "0")
+		if i := strings.Index(s, "."); len(s)-i < 3 {
+			s += strings.Repeat("0", 3-len(s)+i)
+		}
+	default:
+		s = d.Round(prec, Rounding).Text(prec)
+	}
+	// Drop zero decimals, e.g. 100.00.
+	if i := strings.Index(s, "."); i >= 0 && strings.Trim(s[i+1:], "0") == "" {
+		s = s[:i]
+	}
+	return
 }
@@ -1,21 +1,228 @@
 package chat
 
-import "testing"
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
 
-var FormatValueTests = []struct {
-	Value float64
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/exchange"
+	"github.com/koorgoo/vtb24/money"
+	"github.com/koorgoo/vtb24/settings"
+)
+
+var FormatDecimalTests = []struct {
+	Value string
 	S     string
 }{
-	{100.00, "100"},
-	{100.10, "100.10"},
-	{100.01, "100.01"},
-	{100.001, "100"},
+	{"100.00", "100"},
+	{"100.10", "100.10"},
+	{"100.01", "100.01"},
+	{"100.001", "100"},
+	{"1.015", "1.02"},
+	{"2.675", "2.68"},
+	// Halves are rounded to even.
+	{"100.125", "100.12"},
+	{"0.5", "0.50"},
+	{"0.0172", "0.0172"},
+	{"0.01723", "0.0172"},
+	{"0.00001", "0"},
 }
 
-func TestFormatValue(t *testing.T) {
-	for _, tt := range FormatValueTests {
-		if s := FormatValue(tt.Value); s != tt.S {
+func TestFormatDecimal(t *testing.T) {
+	for _, tt := range FormatDecimalTests {
+		d, _ := money.Parse(tt.Value)
+		if s := FormatDecimal(d); s != tt.S {
 			t.Errorf("%v: want %q, got %q", tt.Value, tt.S, s)
 		}
 	}
 }
+
+func TestFormatDecimal_precision(t *testing.T) {
+	tests := []struct {
+		Value string
+		Prec  int
+		S     string
+	}{
+		{"100.5", 0, "100"},
+		{"100.123", 3, "100.123"},
+		{"100.0001", 3, "100"},
+		{"0.0172", 0, "0.0172"},
+	}
+	for _, tt := range tests {
+		d, _ := money.Parse(tt.Value)
+		if s := formatDecimal(d, tt.Prec); s != tt.S {
+			t.Errorf("%v with %d decimals: want %q, got %q", tt.Value, tt.Prec, tt.S, s)
+		}
+	}
+}
+
+var MakeMessageTests = []struct {
+	Query    Query
+	Contains []string
+	Excludes []string
+}{
+	{Query{Amount: money.New(10, 0)}, []string{"USD - *570*", "EUR - *670*", "RUB"}, nil},
+	{Query{Amount: money.New(10, 0), Src: "USD"}, []string{"USD - *570*", "_официальный курс ЦБ РФ_\n\n*10* USD - *580* RUB\nнаценка в ВТБ24 - онлайн: покупка 1.7%, продажа 1.7%"}, []string{"EUR"}},
+	{Query{Amount: money.New(570, 0), Src: "RUB", Dst: "USD"}, []string{"RUB - *9.66*"}, []string{"EUR"}},
+	{Query{Amount: money.New(100, 0), Src: "USD", Dst: "EUR"}, []string{"*100* USD - *81.43* (покупка) *88.06* (продажа) EUR _через RUB_"}, []string{"RUB -"}},
+}
+
+func TestMakeMessage(t *testing.T) {
+	ex := bank.ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("57"), Sell: api.MustValue("59")},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.EUR, Buy: api.MustValue("67"), Sell: api.MustValue("70")},
+		},
+	}, time.Time{})
+	ex = append(ex, bank.NewEx(api.USD, api.RUB, api.GroupCBR, "cbr", time.Time{}, time.Time{}, exchange.New(exchange.Rate{Buy: 58, Sell: 58})))
+	for _, tt := range MakeMessageTests {
+		t.Run(fmt.Sprintf("%+v", tt.Query), func(t *testing.T) {
+			text, _ := MakeMessage(tt.Query, ex, settings.Settings{Groups: []string{api.GroupTele}})
+			for _, s := range tt.Contains {
+				if !strings.Contains(text, s) {
+					t.Errorf("want %q in %q", s, text)
+				}
+			}
+			for _, s := range tt.Excludes {
+				if strings.Contains(text, s) {
+					t.Errorf("want no %q in %q", s, text)
+				}
+			}
+		})
+	}
+}
+
+func TestMakeMessage_freshness(t *testing.T) {
+	active := time.Date(2017, time.October, 1, 6, 0, 0, 0, time.UTC)
+	tests := []struct {
+		Fetched time.Time
+		Stale   bool
+	}{
+		{time.Now(), false},
+		{time.Now().Add(-2 * time.Hour), true},
+	}
+	for _, tt := range tests {
+		ex := bank.ParseEx(&api.Response{
+			Items: []*api.Item{
+				{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("57"), Sell: api.MustValue("59"), DateActiveFrom: api.ItemTime(active)},
+			},
+		}, tt.Fetched)
+		text, _ := MakeMessage(Query{Amount: money.New(1, 0)}, ex, settings.Settings{Groups: []string{api.GroupTele}, MaxAge: time.Hour})
+		if want := "_курс действует с 09:00 01.10.2017 МСК_"; !strings.Contains(text, want) {
+			t.Errorf("want %q in %q", want, text)
+		}
+		if stale := strings.Contains(text, "устаревшими"); stale != tt.Stale {
+			t.Errorf("fetched at %v: want stale %v, got %q", tt.Fetched, tt.Stale, text)
+		}
+	}
+}
+
+func TestMakeMessage_change(t *testing.T) {
+	makeEx := func(buy string, sellArrow string) []bank.Ex {
+		return bank.ParseEx(&api.Response{
+			Items: []*api.Item{
+				{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue(buy), Sell: api.MustValue("80"), SellArrow: sellArrow},
+			},
+		}, time.Time{})
+	}
+	ex := bank.WithPrevious(makeEx("72", "down"), makeEx("64", ""))
+
+	text, _ := MakeMessage(Query{Amount: money.New(10, 0), Src: api.USD}, ex, settings.Settings{Groups: []string{api.GroupTele}})
+	want := "*10* USD - *720* ▲80 (12.5%) (покупка) *800* ▼ (продажа) RUB"
+	if !strings.Contains(text, want) {
+		t.Errorf("want %q in %q", want, text)
+	}
+}
+
+func TestMakeMessage_language(t *testing.T) {
+	ex := bank.ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("57"), Sell: api.MustValue("59")},
+		},
+	}, time.Now().Add(-3*time.Hour-time.Minute))
+	text, _ := MakeMessage(Query{Amount: money.New(10, 0)}, ex, settings.Settings{Groups: []string{api.GroupTele}, Language: "en", MaxAge: time.Hour})
+	for _, want := range []string{
+		"_VTB24 online_",
+		"*10* USD - *570* (buy) *590* (sell) RUB",
+		"not updated for 3 hours",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("want %q in %q", want, text)
+		}
+	}
+}
+
+func TestMakeMessage_tiers(t *testing.T) {
+	ex := bank.ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("57"), Sell: api.MustValue("60")},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("58"), Sell: api.MustValue("59.5"), Gradation: money.New(1000, 0)},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("58.25"), Sell: api.MustValue("59"), Gradation: money.New(5000, 0)},
+		},
+	}, time.Time{})
+	prefs := settings.Settings{Groups: []string{api.GroupTele}}
+
+	tests := []struct {
+		Query    Query
+		Contains []string
+		Excludes []string
+	}{
+		{
+			Query{Amount: money.New(10, 0), Src: api.USD},
+			[]string{"*10* USD - *570* (покупка) *600* (продажа) RUB\nот *1000* USD — курс 58 (покупка) 59.50 (продажа)\nот *5000* USD — курс 58.25 (покупка) 59 (продажа)\n"},
+			nil,
+		},
+		{Query{Amount: money.New(1000, 0), Src: api.USD}, []string{"от *5000* USD"}, []string{"от *1000* USD"}},
+		{Query{Amount: money.New(5000, 0), Src: api.USD}, nil, []string{"от "}},
+		{Query{Amount: money.New(10, 0), Src: api.USD, Side: Sell}, []string{"от *1000* USD — курс 59.50 (продажа)"}, []string{"(покупка)"}},
+		{Query{Amount: money.New(10, 0), Src: api.RUB, Dst: api.USD}, nil, []string{"от "}},
+	}
+	for _, tt := range tests {
+		text, _ := MakeMessage(tt.Query, ex, prefs)
+		for _, s := range tt.Contains {
+			if !strings.Contains(text, s) {
+				t.Errorf("%+v: want %q in %q", tt.Query, s, text)
+			}
+		}
+		for _, s := range tt.Excludes {
+			if strings.Contains(text, s) {
+				t.Errorf("%+v: want no %q in %q", tt.Query, s, text)
+			}
+		}
+	}
+}
+
+func TestMakeTargetMessage(t *testing.T) {
+	ex := bank.ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("57"), Sell: api.MustValue("60")},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("58"), Sell: api.MustValue("59"), Gradation: money.New(1000, 0)},
+		},
+	}, time.Time{})
+	prefs := settings.Settings{Groups: []string{api.GroupTele}}
+
+	tests := []struct {
+		Amount int64
+		Want   string
+	}{
+		{100, "_в ВТБ24 - онлайн_: *6000* RUB\n"},
+		// 1000 USD at the better rate cost less than 990 USD.
+		{990, "_в ВТБ24 - онлайн_: *59000* RUB _(получите 1000 USD)_\n"},
+		{2000, "_в ВТБ24 - онлайн_: *118000* RUB\n"},
+	}
+	for _, tt := range tests {
+		text, _ := MakeTargetMessage(Query{Amount: money.New(tt.Amount, 0), Src: api.RUB, Dst: api.USD}, ex, prefs)
+		if !strings.Contains(text, tt.Want) {
+			t.Errorf("%v: want %q in %q", tt.Amount, tt.Want, text)
+		}
+	}
+
+	// 100/57 USD is rounded up to cents.
+	text, _ := MakeTargetMessage(Query{Amount: money.New(100, 0), Src: api.USD, Dst: api.RUB}, ex, prefs)
+	if want := "*1.76* USD _(получите 100.32 RUB)_"; !strings.Contains(text, want) {
+		t.Errorf("want %q in %q", want, text)
+	}
+}
@@ -0,0 +1,285 @@
+package chat
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/money"
+)
+
+var ErrQuery = errors.New("chat: invalid query")
+
+// Query is an amount to exchange with optional currencies.
+type Query struct {
+	Amount money.Decimal
+	// Src is a currency to exchange. Empty Src means any currency.
+	Src string
+	// Dst is a currency to exchange to. Empty Dst means any currency.
+	Dst string
+	// Side limits exchanges to buy or sell rates of the bank. Empty Side
+	// means both.
+	Side Side
+}
+
+// Side is a side of an exchange from the bank's point of view.
+type Side string
+
+// Sides of exchanges.
+const (
+	Buy  Side = "buy"
+	Sell Side = "sell"
+)
+
+// Currencies lists currency codes understood by ParseQuery.
+var Currencies = []string{
+	api.RUB,
+	api.AUD, api.CAD, api.CHF, api.CNY, api.DKK, api.EUR, api.GBP,
+	api.JPY, api.NOK, api.NZD, api.PLN, api.SEK, api.USD,
+	api.XAG, api.XAU, api.XPD, api.XPT,
+}
+
+var currencySymbols = map[rune]string{
+	'$': api.USD,
+	'€': api.EUR,
+	'£': api.GBP,
+	'¥': api.JPY,
+	'₽': api.RUB,
+}
+
+// currencyNames maps word prefixes to currency codes.
+var currencyNames = []struct {
+	Prefix string
+	Code   string
+}{
+	{"доллар", api.USD},
+	{"бакс", api.USD},
+	{"dollar", api.USD},
+	{"евро", api.EUR},
+	{"euro", api.EUR},
+	{"рубл", api.RUB},
+	{"руб", api.RUB},
+	{"ruble", api.RUB},
+	{"rouble", api.RUB},
+	{"фунт", api.GBP},
+	{"pound", api.GBP},
+	{"франк", api.CHF},
+	{"franc", api.CHF},
+	{"иен", api.JPY},
+	{"йен", api.JPY},
+	{"yen", api.JPY},
+	{"юан", api.CNY},
+	{"yuan", api.CNY},
+	{"злот", api.PLN},
+	{"серебр", api.XAG},
+	{"silver", api.XAG},
+	{"золот", api.XAU},
+	{"gold", api.XAU},
+	{"паллади", api.XPD},
+	{"palladium", api.XPD},
+	{"платин", api.XPT},
+	{"platinum", api.XPT},
+}
+
+var multipliers = map[string]money.Decimal{
+	"k":    money.New(1e3, 0),
+	"к":    money.New(1e3, 0),
+	"тыс":  money.New(1e3, 0),
+	"тыс.": money.New(1e3, 0),
+	"m":    money.New(1e6, 0),
+	"м":    money.New(1e6, 0),
+	"млн":  money.New(1e6, 0),
+}
+
+// stopWords may separate source and destination currencies or denote units
+// of metals.
+var stopWords = map[string]bool{
+	"г":       true,
+	"гр":      true,
+	"грам":    true,
+	"грамм":   true,
+	"грамма":  true,
+	"граммов": true,
+	"g":       true,
+	"в":       true,
+	"на":      true,
+	"за":      true,
+	"to":      true,
+	"for":     true,
+	"in":      true,
+	"->":      true,
+	"→":       true,
+	"=":       true,
+}
+
+var amountRe = regexp.MustCompile(`\d[\d\s.,]*`)
+
+// ParseQuery parses texts like "100", "100 usd", "1.5k €", "5 000,50",
+// "100 долларов в евро".
+func ParseQuery(s string) (q Query, err error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		return r
+	}, s)
+	for r, code := range currencySymbols {
+		s = strings.Replace(s, string(r), " "+code+" ", -1)
+	}
+
+	loc := amountRe.FindStringIndex(s)
+	if loc == nil {
+		return q, ErrQuery
+	}
+	if q.Amount, err = parseAmount(s[loc[0]:loc[1]]); err != nil {
+		return q, ErrQuery
+	}
+
+	after := strings.Fields(s[loc[1]:])
+	if len(after) > 0 {
+		if m, ok := multipliers[after[0]]; ok {
+			q.Amount = q.Amount.Mul(m)
+			after = after[1:]
+		}
+	}
+	if q.Amount.Sign() <= 0 {
+		return q, ErrQuery
+	}
+	words := append(strings.Fields(s[:loc[0]]), after...)
+
+	var codes []string
+	for _, w := range words {
+		if stopWords[w] {
+			continue
+		}
+		code, ok := parseCurrency(w)
+		if !ok {
+			return q, ErrQuery
+		}
+		codes = append(codes, code)
+	}
+
+	switch len(codes) {
+	case 0:
+	case 1:
+		q.Src = codes[0]
+	case 2:
+		q.Src, q.Dst = codes[0], codes[1]
+	default:
+		return q, ErrQuery
+	}
+	if q.Src != "" && q.Src == q.Dst {
+		return q, ErrQuery
+	}
+	return q, nil
+}
+
+// targetWords start texts asking how much to pay for an amount.
+var targetWords = map[string]bool{
+	"нужно": true,
+	"надо":  true,
+	"need":  true,
+}
+
+// ParseTarget parses texts like "нужно 1000 usd" or "need 100 eur for usd"
+// asking how much of q.Src to pay to get q.Amount of q.Dst. q.Src defaults
+// to RUB.
+func ParseTarget(s string) (q Query, err error) {
+	v := strings.Fields(strings.ToLower(s))
+	if len(v) < 2 || !targetWords[v[0]] {
+		return q, ErrQuery
+	}
+	t, err := ParseQuery(strings.Join(v[1:], " "))
+	if err != nil || t.Src == "" {
+		return q, ErrQuery
+	}
+	q = Query{Amount: t.Amount, Src: t.Dst, Dst: t.Src}
+	if q.Src == "" {
+		q.Src = api.RUB
+	}
+	if q.Src == q.Dst {
+		return Query{}, ErrQuery
+	}
+	return q, nil
+}
+
+func parseAmount(s string) (money.Decimal, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), ".,")
+
+	// Spaces separate thousands only, e.g. "5 000,50" but not "100 2".
+	groups := strings.Fields(s)
+	for i, g := range groups[1:] {
+		if strings.ContainsAny(groups[i], ".,") {
+			return money.Decimal{}, ErrQuery
+		}
+		if n := strings.IndexAny(g, ".,"); n >= 0 {
+			g = g[:n]
+		}
+		if len(g) != 3 {
+			return money.Decimal{}, ErrQuery
+		}
+	}
+	s = strings.Join(groups, "")
+
+	// A single comma or dot is decimal, e.g. "1,500" is 1.5. Repeated or
+	// mixed separators separate thousands, and the latter one is decimal.
+	var sep, dec string
+	dot, comma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+	switch {
+	case dot >= 0 && comma >= 0 && dot > comma:
+		sep, dec = ",", "."
+	case dot >= 0 && comma >= 0:
+		sep, dec = ".", ","
+	case strings.Count(s, ",") > 1:
+		sep, dec = ",", "."
+	case strings.Count(s, ".") > 1:
+		sep, dec = ".", ","
+	}
+	if sep != "" {
+		n, frac := s, ""
+		if i := strings.Index(s, dec); i >= 0 {
+			n, frac = s[:i], "."+s[i+1:]
+		}
+		// Thousands have 3 digits, and the first group has no leading 0.
+		groups := strings.Split(n, sep)
+		if g := groups[0]; g == "" || g[0] == '0' || len(g) > 3 {
+			return money.Decimal{}, ErrQuery
+		}
+		for _, g := range groups[1:] {
+			if len(g) != 3 {
+				return money.Decimal{}, ErrQuery
+			}
+		}
+		s = strings.Join(groups, "") + frac
+	} else {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	return money.Parse(s)
+}
+
+func parseCurrency(w string) (string, bool) {
+	code := strings.ToUpper(w)
+	for _, c := range Currencies {
+		if c == code {
+			return c, true
+		}
+	}
+	switch w {
+	case "р", "р.":
+		return api.RUB, true
+	}
+	for _, n := range currencyNames {
+		if strings.HasPrefix(w, n.Prefix) {
+			return n.Code, true
+		}
+	}
+	return "", false
+}
+
+func (q *Query) match(e bank.Ex) bool {
+	return (q.Src == "" || q.Src == e.Src()) && (q.Dst == "" || q.Dst == e.Dst())
+}
@@ -0,0 +1,107 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/koorgoo/vtb24/money"
+)
+
+var ParseQueryTests = []struct {
+	Text  string
+	Query Query
+	OK    bool
+}{
+	{"100", Query{Amount: money.New(100, 0)}, true},
+	{"100.5", Query{Amount: money.New(1005, 1)}, true},
+	{"100,5", Query{Amount: money.New(1005, 1)}, true},
+	{"5 000,50", Query{Amount: money.New(50005, 1)}, true},
+	{"5 000", Query{Amount: money.New(5000, 0)}, true},
+	{"1,000.50", Query{Amount: money.New(10005, 1)}, true},
+	{"1.000.000", Query{Amount: money.New(1000000, 0)}, true},
+	{"100 usd", Query{Amount: money.New(100, 0), Src: "USD"}, true},
+	{"USD 100", Query{Amount: money.New(100, 0), Src: "USD"}, true},
+	{"$100", Query{Amount: money.New(100, 0), Src: "USD"}, true},
+	{"1.5k €", Query{Amount: money.New(1500, 0), Src: "EUR"}, true},
+	{"2 тыс рублей", Query{Amount: money.New(2000, 0), Src: "RUB"}, true},
+	{"100 долларов в евро", Query{Amount: money.New(100, 0), Src: "USD", Dst: "EUR"}, true},
+	{"100 eur to usd", Query{Amount: money.New(100, 0), Src: "EUR", Dst: "USD"}, true},
+	{"1 млн ₽", Query{Amount: money.New(1000000, 0), Src: "RUB"}, true},
+	{"10 г золота", Query{Amount: money.New(10, 0), Src: "XAU"}, true},
+	{"2 грамма золота", Query{Amount: money.New(2, 0), Src: "XAU"}, true},
+	{"5 грам серебра", Query{Amount: money.New(5, 0), Src: "XAG"}, true},
+	{"5 xag", Query{Amount: money.New(5, 0), Src: "XAG"}, true},
+	{"", Query{}, false},
+	{"hello", Query{}, false},
+	{"100 apples", Query{}, false},
+	{"100 usd usd", Query{}, false},
+	{"1,000", Query{Amount: money.New(1, 0)}, true},
+	{"1,000 usd", Query{Amount: money.New(1, 0), Src: "USD"}, true},
+	{"0,001", Query{Amount: money.New(1, 3)}, true},
+	{"1,500", Query{Amount: money.New(15, 1)}, true},
+	{"1.500", Query{Amount: money.New(15, 1)}, true},
+	{"1,500 г золота", Query{Amount: money.New(15, 1), Src: "XAU"}, true},
+	{"1,000,000", Query{Amount: money.New(1000000, 0)}, true},
+	{"1.000,5", Query{Amount: money.New(10005, 1)}, true},
+	{"0,001,000", Query{}, false},
+	{"1,00,000", Query{}, false},
+	{"1,5.000", Query{}, false},
+	{"1,50", Query{Amount: money.New(15, 1)}, true},
+	{"10 000 000", Query{Amount: money.New(10000000, 0)}, true},
+	{"100 2", Query{}, false},
+	{"100 20 usd", Query{}, false},
+	{"1,5 000", Query{}, false},
+	{"0 usd", Query{}, false},
+	{"0,00", Query{}, false},
+	{"100 usd eur rub", Query{}, false},
+}
+
+func equalQuery(a, b Query) bool {
+	if a.Amount.Cmp(b.Amount) != 0 {
+		return false
+	}
+	a.Amount, b.Amount = money.Decimal{}, money.Decimal{}
+	return a == b
+}
+
+func TestParseQuery(t *testing.T) {
+	for _, tt := range ParseQueryTests {
+		t.Run(tt.Text, func(t *testing.T) {
+			q, err := ParseQuery(tt.Text)
+			if ok := (err == nil); ok != tt.OK {
+				t.Fatalf("error: want %v, got %v: %v", tt.OK, ok, err)
+			}
+			if tt.OK && !equalQuery(q, tt.Query) {
+				t.Errorf("want %+v, got %+v", tt.Query, q)
+			}
+		})
+	}
+}
+
+var ParseTargetTests = []struct {
+	Text  string
+	Query Query
+	OK    bool
+}{
+	{"нужно 1000 usd", Query{Amount: money.New(1000, 0), Src: "RUB", Dst: "USD"}, true},
+	{"Need $100", Query{Amount: money.New(100, 0), Src: "RUB", Dst: "USD"}, true},
+	{"надо 100 евро за доллары", Query{Amount: money.New(100, 0), Src: "USD", Dst: "EUR"}, true},
+	{"need 5000 rub for usd", Query{Amount: money.New(5000, 0), Src: "USD", Dst: "RUB"}, true},
+	{"нужно 1000 rub", Query{}, false},
+	{"нужно 1000", Query{}, false},
+	{"нужно 0 usd", Query{}, false},
+	{"1000 usd", Query{}, false},
+}
+
+func TestParseTarget(t *testing.T) {
+	for _, tt := range ParseTargetTests {
+		t.Run(tt.Text, func(t *testing.T) {
+			q, err := ParseTarget(tt.Text)
+			if ok := (err == nil); ok != tt.OK {
+				t.Fatalf("error: want %v, got %v: %v", tt.OK, ok, err)
+			}
+			if tt.OK && !equalQuery(q, tt.Query) {
+				t.Errorf("want %+v, got %+v", tt.Query, q)
+			}
+		})
+	}
+}
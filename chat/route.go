@@ -0,0 +1,29 @@
+package chat
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/koorgoo/telegram"
+	"github.com/koorgoo/vtb24/route"
+	"github.com/koorgoo/vtb24/settings"
+)
+
+// MakeRoutesMessage lists up to limit routes exchanging q.Amount of q.Src to
+// q.Dst formatted with chat preferences.
+func MakeRoutesMessage(q Query, routes []route.Route, limit int, prefs settings.Settings) (text string, mode telegram.ParseMode) {
+	if len(routes) > limit {
+		routes = routes[:limit]
+	}
+	f := newFormatter(prefs)
+
+	var buf bytes.Buffer
+	for i, r := range routes {
+		fmt.Fprintf(&buf, "%d. *%s* %s\n", i+1, f.amount(r.Amount, q.Dst), q.Dst)
+		for _, e := range r.Steps {
+			fmt.Fprintf(&buf, "%s › %s _%s_\n", e.Src(), e.Dst(), f.p.Group(e.Group()))
+		}
+		fmt.Fprintln(&buf)
+	}
+	return buf.String(), telegram.ModeMarkdown
+}
@@ -0,0 +1,39 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/money"
+	"github.com/koorgoo/vtb24/route"
+	"github.com/koorgoo/vtb24/settings"
+)
+
+func TestMakeRoutesMessage(t *testing.T) {
+	ex := bank.ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("57"), Sell: api.MustValue("59")},
+		},
+	}, time.Time{})
+	routes := []route.Route{{Steps: ex, Amount: money.New(1234567, 4)}}
+	zero, four := 0, 4
+
+	tests := []struct {
+		Prefs settings.Settings
+		Want  string
+	}{
+		{settings.Settings{}, "1. *123.46* RUB\nUSD › RUB _в ВТБ24 - онлайн_\n"},
+		{settings.Settings{Precision: &zero, Language: "en"}, "1. *123* RUB\nUSD › RUB _VTB24 online_\n"},
+		// Amounts have no more decimals than kopecks.
+		{settings.Settings{Precision: &four}, "1. *123.46* RUB\n"},
+	}
+	for _, tt := range tests {
+		text, _ := MakeRoutesMessage(Query{Amount: money.New(10, 0), Src: api.USD, Dst: api.RUB}, routes, 5, tt.Prefs)
+		if !strings.Contains(text, tt.Want) {
+			t.Errorf("want %q in %q", tt.Want, text)
+		}
+	}
+}
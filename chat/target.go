@@ -0,0 +1,82 @@
+package chat
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/koorgoo/telegram"
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/locale"
+	"github.com/koorgoo/vtb24/money"
+	"github.com/koorgoo/vtb24/settings"
+)
+
+// MakeTargetMessage tells how much of q.Src to pay in groups of prefs to get
+// q.Amount of q.Dst.
+func MakeTargetMessage(q Query, ex []bank.Ex, prefs settings.Settings) (text string, mode telegram.ParseMode) {
+	f := newFormatter(prefs)
+
+	if q.Src != api.RUB && q.Dst != api.RUB {
+		ex = append(ex[:len(ex):len(ex)], bank.CrossEx(ex, q.Src, q.Dst, api.RUB)...)
+	}
+
+	var buf bytes.Buffer
+	var active, fetched time.Time
+
+	for _, group := range prefs.Groups {
+		for _, e := range ex {
+			if e.Group() != group {
+				continue
+			}
+			for _, e := range []bank.Ex{e, bank.Invert(e)} {
+				s, ok := f.target(q, e)
+				if !ok {
+					continue
+				}
+				if buf.Len() == 0 {
+					fmt.Fprintln(&buf, f.p.Sprintf(locale.MsgTarget, f.value(q.Amount), f.unit(e, q.Dst)))
+					fmt.Fprintln(&buf)
+				}
+				fmt.Fprintln(&buf, s)
+				if e.ActiveFrom().After(active) {
+					active = e.ActiveFrom()
+				}
+				if fetched.IsZero() || e.FetchedAt().Before(fetched) {
+					fetched = e.FetchedAt()
+				}
+			}
+		}
+	}
+
+	if buf.Len() > 0 {
+		fmt.Fprintln(&buf)
+		f.writeFreshness(&buf, active, fetched)
+	}
+	return buf.String(), telegram.ModeMarkdown
+}
+
+// target returns an amount of e.Src() to pay to get q.Amount of e.Dst().
+// Thresholds may make the amount exchanged into more than q.Amount.
+func (f *formatter) target(q Query, e bank.Ex) (s string, ok bool) {
+	if e.Src() != q.Src || e.Dst() != q.Dst {
+		return
+	}
+	n := q.Amount
+	x, err := e.BuyForDecimal(n)
+	if err != nil {
+		return
+	}
+	// Round up not to pay less than needed.
+	x = money.RoundMinor(x, e.Src(), money.Ceil).Round(f.prec, money.Ceil)
+
+	s = fmt.Sprintf("_%s_: *%v* %v", f.p.Group(e.Group()), f.amount(x, e.Src()), f.unit(e, e.Src()))
+	if y, err := e.BuyDecimal(x); err == nil && f.amount(y, e.Dst()) != f.amount(n, e.Dst()) {
+		s += " " + f.p.Sprintf(locale.MsgTargetGot, f.amount(y, e.Dst()), f.unit(e, e.Dst()))
+	}
+	if via, ok := bank.Via(e); ok {
+		s += " " + f.p.Sprintf(locale.MsgVia, via)
+	}
+	return s, true
+}
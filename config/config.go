@@ -5,32 +5,111 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
+
+	"github.com/koorgoo/vtb24/api"
 )
 
-const DefaultRatesTimeout = Duration(5 * time.Minute)
+const (
+	DefaultRatesTimeout   = Duration(5 * time.Minute)
+	DefaultDataDir        = "data"
+	DefaultRequestTimeout = Duration(10 * time.Second)
+	DefaultRequestRetries = 3
+	DefaultMaxRateJump    = 10
+	DefaultMaxRatesAge    = Duration(time.Hour)
+	DefaultHistoryAge     = Duration(365 * 24 * time.Hour)
+
+	DefaultDonateFooterInterval = Duration(7 * 24 * time.Hour)
+)
+
+// DefaultPairs are currency pairs shown when no pairs are configured.
+var DefaultPairs = []string{"USD/RUB", "EUR/RUB"}
 
 type Config struct {
-	WebAddr       string        `json:"web_addr"`
-	TelegramToken string        `json:"telegram_token"`
-	RatesTimeout  Duration      `json:"rates_timeout"`
-	Donate        *DonateConfig `json:"donate"`
+	WebAddr       string   `json:"web_addr"`
+	TelegramToken string   `json:"telegram_token"`
+	RatesTimeout  Duration `json:"rates_timeout"`
+	DataDir       string   `json:"data_dir"`
+	Pairs         []string `json:"pairs"`
+
+	RequestTimeout Duration `json:"request_timeout"`
+	RequestRetries *int     `json:"request_retries"`
+
+	// MaxRateJump is a maximal change of a rate in percent between
+	// snapshots. Larger changes must be confirmed by the next snapshot.
+	MaxRateJump float64 `json:"max_rate_jump"`
+	// MaxRatesAge is an age of rates after which replies warn that rates
+	// may be outdated.
+	MaxRatesAge Duration `json:"max_rates_age"`
+	// HistoryAge is an age of rates after which they are removed from
+	// history.
+	HistoryAge Duration `json:"history_age"`
+
+	Webhook *WebhookConfig `json:"webhook"`
+	Donate  *DonateConfig  `json:"donate"`
 }
 
+// WebhookConfig enables receiving updates on WebAddr instead of long polling.
+type WebhookConfig struct {
+	Path string `json:"path"`
+	// SecretToken is sent by Telegram with each update.
+	SecretToken string `json:"secret_token"`
+	// URL is a public URL of Path. When set, the webhook is registered on
+	// start.
+	URL string `json:"url"`
+}
+
+// DonateConfig enables the /donate command and donation footers in replies.
 type DonateConfig struct {
 	CardNumber  string `json:"card_number"`
 	WishListURL string `json:"wish_list_url"`
+	// FooterInterval is a minimal interval between footers in a chat.
+	FooterInterval Duration `json:"footer_interval"`
 }
 
 var (
-	errWebAddr       = errors.New("invalid web addr")
-	errTelegramToken = errors.New("invalid telegram token")
+	errWebAddr        = errors.New("invalid web addr")
+	errTelegramToken  = errors.New("invalid telegram token")
+	errRequestRetries = errors.New("invalid request retries")
+	errMaxRateJump    = errors.New("invalid max rate jump")
+	errMaxRatesAge    = errors.New("invalid max rates age")
+	errHistoryAge     = errors.New("invalid history age")
+	errWebhookPath    = errors.New("invalid webhook path")
+	errWebhookSecret  = errors.New("invalid webhook secret token")
+	errDonate         = errors.New("invalid donate: no card number or wish list url")
+	errDonateInterval = errors.New("invalid donate footer interval")
 )
 
 func (c *Config) setDefaults() {
 	if c.RatesTimeout == 0 {
 		c.RatesTimeout = DefaultRatesTimeout
 	}
+	if c.DataDir == "" {
+		c.DataDir = DefaultDataDir
+	}
+	if len(c.Pairs) == 0 {
+		c.Pairs = DefaultPairs
+	}
+	if c.RequestTimeout == 0 {
+		c.RequestTimeout = DefaultRequestTimeout
+	}
+	if c.MaxRateJump == 0 {
+		c.MaxRateJump = DefaultMaxRateJump
+	}
+	if c.MaxRatesAge == 0 {
+		c.MaxRatesAge = DefaultMaxRatesAge
+	}
+	if c.HistoryAge == 0 {
+		c.HistoryAge = DefaultHistoryAge
+	}
+	if c.RequestRetries == nil {
+		n := DefaultRequestRetries
+		c.RequestRetries = &n
+	}
+	if c.Donate != nil && c.Donate.FooterInterval == 0 {
+		c.Donate.FooterInterval = DefaultDonateFooterInterval
+	}
 }
 
 func (c *Config) validate() error {
@@ -40,6 +119,66 @@ func (c *Config) validate() error {
 	if c.TelegramToken == "" {
 		return errTelegramToken
 	}
+	if *c.RequestRetries < 0 {
+		return errRequestRetries
+	}
+	if c.MaxRateJump < 0 {
+		return errMaxRateJump
+	}
+	if c.MaxRatesAge < 0 {
+		return errMaxRatesAge
+	}
+	if c.HistoryAge < 0 {
+		return errHistoryAge
+	}
+	for _, pair := range c.Pairs {
+		if _, _, err := api.ParsePair(pair); err != nil {
+			return err
+		}
+	}
+	if c.Webhook != nil {
+		if err := c.Webhook.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Donate != nil {
+		return c.Donate.validate()
+	}
+	return nil
+}
+
+func (c *DonateConfig) validate() error {
+	if c.CardNumber == "" && c.WishListURL == "" {
+		return errDonate
+	}
+	if c.FooterInterval < 0 {
+		return errDonateInterval
+	}
+	return nil
+}
+
+// reservedPaths are served by main on WebAddr besides a webhook. Paths ending
+// with a slash reserve subtrees.
+var reservedPaths = []string{"/metrics", "/v1/"}
+
+func (c *WebhookConfig) validate() error {
+	if !strings.HasPrefix(c.Path, "/") {
+		return errWebhookPath
+	}
+	for _, p := range reservedPaths {
+		if c.Path == p || strings.HasSuffix(p, "/") && strings.HasPrefix(c.Path, p) {
+			return errWebhookPath
+		}
+	}
+	// Telegram allows 1-256 characters A-Z, a-z, 0-9, _ and -.
+	if len(c.SecretToken) == 0 || len(c.SecretToken) > 256 {
+		return errWebhookSecret
+	}
+	for _, r := range c.SecretToken {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return errWebhookSecret
+		}
+	}
 	return nil
 }
 
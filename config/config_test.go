@@ -13,16 +13,53 @@ var ParseJSONTests = []struct {
 }{
 	{
 		"testdata/valid.json",
-		Config{WebAddr: ":8000", TelegramToken: "test", RatesTimeout: Duration(time.Minute)},
+		Config{
+			WebAddr:        ":8000",
+			TelegramToken:  "test",
+			RatesTimeout:   Duration(time.Minute),
+			DataDir:        "/var/lib/vtb24",
+			Pairs:          []string{"USD/RUB", "EUR/USD"},
+			RequestTimeout: Duration(5 * time.Second),
+			RequestRetries: intPtr(0),
+			MaxRateJump:    5,
+			MaxRatesAge:    Duration(30 * time.Minute),
+			HistoryAge:     Duration(30 * 24 * time.Hour),
+			Webhook: &WebhookConfig{
+				Path:        "/telegram",
+				SecretToken: "s3cret_token",
+				URL:         "https://example.com/telegram",
+			},
+			Donate: &DonateConfig{
+				CardNumber:     "4276 0000 0000 0000",
+				WishListURL:    "https://example.com/wishlist",
+				FooterInterval: Duration(72 * time.Hour),
+			},
+		},
 		true,
 	},
 	{
 		"testdata/valid-with-defaults.json",
-		Config{WebAddr: ":8000", TelegramToken: "test", RatesTimeout: DefaultRatesTimeout},
+		Config{
+			WebAddr:        ":8000",
+			TelegramToken:  "test",
+			RatesTimeout:   DefaultRatesTimeout,
+			DataDir:        DefaultDataDir,
+			Pairs:          DefaultPairs,
+			RequestTimeout: DefaultRequestTimeout,
+			RequestRetries: intPtr(DefaultRequestRetries),
+			MaxRateJump:    DefaultMaxRateJump,
+			MaxRatesAge:    DefaultMaxRatesAge,
+			HistoryAge:     DefaultHistoryAge,
+		},
 		true,
 	},
 	{"testdata/no-web-addr.json", Config{}, false},
 	{"testdata/no-telegram-token.json", Config{}, false},
+	{"testdata/invalid-pair.json", Config{}, false},
+	{"testdata/invalid-retries.json", Config{}, false},
+	{"testdata/invalid-webhook.json", Config{}, false},
+	{"testdata/reserved-webhook-path.json", Config{}, false},
+	{"testdata/invalid-donate.json", Config{}, false},
 	{"testdata/not-json.json", Config{}, false},
 	{"testdata/does-not-exist.json", Config{}, false},
 }
@@ -40,3 +77,25 @@ func TestParseJSON(t *testing.T) {
 		})
 	}
 }
+
+func intPtr(n int) *int { return &n }
+
+func TestWebhookConfig_validate(t *testing.T) {
+	tests := []struct {
+		Path string
+		Err  error
+	}{
+		{"/telegram", nil},
+		{"/v1", nil},
+		{"telegram", errWebhookPath},
+		{"/metrics", errWebhookPath},
+		{"/v1/", errWebhookPath},
+		{"/v1/telegram", errWebhookPath},
+	}
+	for _, tt := range tests {
+		c := WebhookConfig{Path: tt.Path, SecretToken: "secret"}
+		if err := c.validate(); err != tt.Err {
+			t.Errorf("%s: want %v, got %v", tt.Path, tt.Err, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package donate keeps per-chat state of donation footers.
+package donate
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/koorgoo/vtb24/jsonfile"
+)
+
+// Details are ways to donate. Empty fields are not shown.
+type Details struct {
+	CardNumber  string
+	WishListURL string
+}
+
+// State is donation state of a chat.
+type State struct {
+	// Hidden is true when the chat asked not to show footers again.
+	Hidden bool `json:"hidden,omitempty"`
+	// ShownAt is time a footer was last shown at or, before the first
+	// footer, time the chat was first seen at.
+	ShownAt time.Time `json:"shown_at"`
+	// Shown is a number of footers shown to the chat.
+	Shown int `json:"shown,omitempty"`
+}
+
+// Store keeps donation state of chats in a JSON file.
+type Store struct {
+	filename string
+	interval time.Duration
+
+	mu sync.Mutex
+	m  map[int64]State
+}
+
+// Open loads state from filename, which may not exist yet. Footers are shown
+// to a chat at most once per interval.
+func Open(filename string, interval time.Duration) (*Store, error) {
+	s := &Store{filename: filename, interval: interval, m: map[int64]State{}}
+	if err := jsonfile.Load(filename, &s.m); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("donate: %s", err)
+	}
+	return s, nil
+}
+
+// Get returns state of a chat.
+func (s *Store) Get(chatID int64) State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m[chatID]
+}
+
+// Footer reports whether a footer should be shown to a chat at now and
+// counts it as shown. The first footer is shown an interval after the chat
+// is first seen.
+func (s *Store) Footer(chatID int64, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.m[chatID]
+	switch {
+	case !ok:
+		s.m[chatID] = State{ShownAt: now}
+		return false, s.save()
+	case v.Hidden || now.Sub(v.ShownAt) < s.interval:
+		return false, nil
+	}
+	v.ShownAt = now
+	v.Shown++
+	s.m[chatID] = v
+	return true, s.save()
+}
+
+// SetHidden hides or shows footers in a chat.
+func (s *Store) SetHidden(chatID int64, hidden bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := s.m[chatID]
+	v.Hidden = hidden
+	s.m[chatID] = v
+	return s.save()
+}
+
+func (s *Store) save() error {
+	if err := jsonfile.Save(s.filename, s.m); err != nil {
+		return fmt.Errorf("donate: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package donate
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_Footer(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "donate.json")
+	s, err := Open(filename, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2017, time.October, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		After time.Duration
+		Show  bool
+	}{
+		{0, false},
+		{30 * time.Minute, false},
+		{time.Hour, true},
+		{90 * time.Minute, false},
+		{2 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		show, err := s.Footer(1, start.Add(tt.After))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if show != tt.Show {
+			t.Errorf("after %v: want %v, got %v", tt.After, tt.Show, show)
+		}
+	}
+
+	if err = s.SetHidden(1, true); err != nil {
+		t.Fatal(err)
+	}
+	s, err = Open(filename, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := s.Get(1); !v.Hidden || v.Shown != 2 {
+		t.Errorf("want hidden state shown 2 times, got %+v", v)
+	}
+	if show, _ := s.Footer(1, start.Add(10*time.Hour)); show {
+		t.Error("want no footer in a hidden chat")
+	}
+}
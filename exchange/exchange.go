@@ -3,6 +3,8 @@ package exchange
 import (
 	"errors"
 	"sort"
+
+	"github.com/koorgoo/vtb24/money"
 )
 
 var (
@@ -16,12 +18,41 @@ var errThreshold = errors.New("")
 
 type Func func(float64) (float64, error)
 
+// DecimalFunc is Func in exact decimal arithmetic.
+type DecimalFunc func(money.Decimal) (money.Decimal, error)
+
+// Float returns f computing with decimals. It converts floats with
+// money.FromFloat.
+func (f DecimalFunc) Float() Func {
+	return func(x float64) (float64, error) {
+		y, err := f(money.FromFloat(x))
+		return y.Float(), err
+	}
+}
+
 type Interface interface {
+	// Buy, Sell, BuyFor and SellFor are decimal methods for floats.
 	Buy(float64) (float64, error)
 	Sell(float64) (float64, error)
+	BuyFor(float64) (float64, error)
+	SellFor(float64) (float64, error)
+
+	BuyDecimal(money.Decimal) (money.Decimal, error)
+	SellDecimal(money.Decimal) (money.Decimal, error)
+	// BuyForDecimal returns the minimal amount which BuyDecimal exchanges
+	// into at least the provided amount. Thresholds apply to the returned
+	// amount.
+	BuyForDecimal(money.Decimal) (money.Decimal, error)
+	// SellForDecimal is BuyForDecimal for SellDecimal.
+	SellForDecimal(money.Decimal) (money.Decimal, error)
+
 	Rates() []Rate
+	// Tiers returns tier tables of buy and sell rates sorted by thresholds.
+	Tiers() (buy, sell Tiers)
 }
 
+// Rate is a buy and sell rate. Buy and Sell are kept for compatibility,
+// rates created with NewRate are exact decimals.
 type Rate struct {
 	Buy  float64
 	Sell float64
@@ -32,56 +63,153 @@ type Rate struct {
 	Threshold Threshold
 
 	inverted bool
+
+	exact     bool
+	buy, sell money.Decimal
+}
+
+// NewRate returns a rate with exact decimal buy and sell rates.
+func NewRate(buy, sell money.Decimal, th Threshold) Rate {
+	return Rate{
+		Buy:       buy.Float(),
+		Sell:      sell.Float(),
+		Threshold: th,
+		exact:     true,
+		buy:       buy,
+		sell:      sell,
+	}
+}
+
+// BuyRate returns an exact buy rate. Rates created without NewRate convert
+// Buy with money.FromFloat.
+func (r *Rate) BuyRate() money.Decimal {
+	if r.exact {
+		return r.buy
+	}
+	return money.FromFloat(r.Buy)
+}
+
+// SellRate is BuyRate for Sell.
+func (r *Rate) SellRate() money.Decimal {
+	if r.exact {
+		return r.sell
+	}
+	return money.FromFloat(r.Sell)
+}
+
+// buyThreshold and sellThreshold return exact thresholds. A rate without a
+// threshold applies to any amount.
+func (r *Rate) buyThreshold() money.Decimal {
+	if r.Threshold == nil {
+		return money.Decimal{}
+	}
+	return r.Threshold.BuyDecimal()
 }
 
-func (r *Rate) doBuy(x float64) (float64, error)  { return doExchange(x, r.Buy, r.Threshold.Buy()) }
-func (r *Rate) doSell(x float64) (float64, error) { return doExchange(x, r.Sell, r.Threshold.Sell()) }
+func (r *Rate) sellThreshold() money.Decimal {
+	if r.Threshold == nil {
+		return money.Decimal{}
+	}
+	return r.Threshold.SellDecimal()
+}
+
+func (r *Rate) doBuy(x money.Decimal) (money.Decimal, error) {
+	return doExchange(x, r.BuyRate(), r.buyThreshold())
+}
+
+func (r *Rate) doSell(x money.Decimal) (money.Decimal, error) {
+	return doExchange(x, r.SellRate(), r.sellThreshold())
+}
 
-func doExchange(x, rate, threshold float64) (y float64, err error) {
+// solve returns the minimal amount x exchanged at rate into at least y
+// which is in [threshold, upper). Nil upper means no upper bound.
+func solve(y, rate, threshold money.Decimal, upper *money.Decimal) (x money.Decimal, err error) {
 	switch {
-	case x < 0:
+	case y.Sign() < 0:
 		err = ErrNegativeAmount
-	case x < threshold:
+	case rate.Sign() <= 0:
 		err = errThreshold
 	default:
-		y = x * rate
+		if x = y.Div(rate); x.Cmp(threshold) < 0 {
+			x = threshold
+		}
+		if upper != nil && x.Cmp(*upper) >= 0 {
+			x, err = money.Decimal{}, errThreshold
+		}
+	}
+	return
+}
+
+func doExchange(x, rate, threshold money.Decimal) (y money.Decimal, err error) {
+	switch {
+	case x.Sign() < 0:
+		err = ErrNegativeAmount
+	case x.Cmp(threshold) < 0:
+		err = errThreshold
+	default:
+		y = x.Mul(rate)
 	}
 	return
 }
 
 func (r *Rate) Invert() *Rate {
 	b, s := r.invertRates()
-	th := r.invertThreshold()
-	return &Rate{Buy: b, Sell: s, Threshold: th, inverted: !r.inverted}
+	v := NewRate(b, s, r.invertThreshold())
+	v.inverted = !r.inverted
+	return &v
 }
 
-func (r *Rate) invertRates() (buy, sell float64) {
-	return 1 / r.Sell, 1 / r.Buy
+// invertRates returns exact inverse rates. Zero rates stay zero.
+func (r *Rate) invertRates() (buy, sell money.Decimal) {
+	return inverse(r.SellRate()), inverse(r.BuyRate())
+}
+
+func inverse(d money.Decimal) money.Decimal {
+	return quo(money.New(1, 0), d)
+}
+
+// quo returns d/e or 0 when e is 0.
+func quo(d, e money.Decimal) money.Decimal {
+	if e.Sign() == 0 {
+		return money.Decimal{}
+	}
+	return d.Div(e)
 }
 
 func (r *Rate) invertThreshold() Threshold {
-	b, s := r.Threshold.Buy(), r.Threshold.Sell()
+	b, s := r.buyThreshold(), r.sellThreshold()
 	if r.inverted {
-		s, b = b/r.Buy, s/r.Sell
+		s, b = quo(b, r.BuyRate()), quo(s, r.SellRate())
 	} else {
-		s, b = b*r.Buy, s*r.Sell
+		s, b = b.Mul(r.BuyRate()), s.Mul(r.SellRate())
 	}
-	return NewThreshold(b, s)
+	return NewDecimalThreshold(b, s)
 }
 
+// Threshold is a minimal amount to exchange at a rate. Buy and Sell are kept
+// for compatibility.
 type Threshold interface {
 	Buy() float64
 	Sell() float64
+	BuyDecimal() money.Decimal
+	SellDecimal() money.Decimal
 }
 
 func NewThreshold(buy, sell float64) Threshold {
+	return NewDecimalThreshold(money.FromFloat(buy), money.FromFloat(sell))
+}
+
+// NewDecimalThreshold returns a threshold with exact decimal amounts.
+func NewDecimalThreshold(buy, sell money.Decimal) Threshold {
 	return &threshold{buy: buy, sell: sell}
 }
 
-type threshold struct{ buy, sell float64 }
+type threshold struct{ buy, sell money.Decimal }
 
-func (t *threshold) Buy() float64  { return t.buy }
-func (t *threshold) Sell() float64 { return t.sell }
+func (t *threshold) Buy() float64               { return t.buy.Float() }
+func (t *threshold) Sell() float64              { return t.sell.Float() }
+func (t *threshold) BuyDecimal() money.Decimal  { return t.buy }
+func (t *threshold) SellDecimal() money.Decimal { return t.sell }
 
 // New returns an Interface.
 //
@@ -93,7 +221,6 @@ func New(rates ...Rate) Interface {
 	case 1:
 		return newRateEx(&rates[0])
 	default:
-		// TODO: panic when rate has nil threshold?
 		return newRatesEx(rates)
 	}
 }
@@ -107,16 +234,45 @@ func newRateEx(rate *Rate) Interface {
 
 type rateEx struct{ Rate *Rate }
 
-func (e *rateEx) Buy(x float64) (float64, error)  { return e.Rate.doBuy(x) }
-func (e *rateEx) Sell(x float64) (float64, error) { return e.Rate.doSell(x) }
-func (e *rateEx) Rates() []Rate                   { return []Rate{*e.Rate} }
+func (e *rateEx) Buy(x float64) (float64, error)     { return DecimalFunc(e.BuyDecimal).Float()(x) }
+func (e *rateEx) Sell(x float64) (float64, error)    { return DecimalFunc(e.SellDecimal).Float()(x) }
+func (e *rateEx) BuyFor(y float64) (float64, error)  { return DecimalFunc(e.BuyForDecimal).Float()(y) }
+func (e *rateEx) SellFor(y float64) (float64, error) { return DecimalFunc(e.SellForDecimal).Float()(y) }
+func (e *rateEx) Rates() []Rate                      { return []Rate{*e.Rate} }
+
+func (e *rateEx) BuyDecimal(x money.Decimal) (money.Decimal, error)  { return e.Rate.doBuy(x) }
+func (e *rateEx) SellDecimal(x money.Decimal) (money.Decimal, error) { return e.Rate.doSell(x) }
+
+func (e *rateEx) Tiers() (buy, sell Tiers) {
+	r := e.Rate
+	return Tiers{{From: r.buyThreshold(), Rate: r.BuyRate()}}, Tiers{{From: r.sellThreshold(), Rate: r.SellRate()}}
+}
+
+func (e *rateEx) BuyForDecimal(y money.Decimal) (money.Decimal, error) {
+	return solveOne(y, e.Rate.BuyRate())
+}
+
+func (e *rateEx) SellForDecimal(y money.Decimal) (money.Decimal, error) {
+	return solveOne(y, e.Rate.SellRate())
+}
+
+func solveOne(y, rate money.Decimal) (money.Decimal, error) {
+	x, err := solve(y, rate, money.Decimal{}, nil)
+	if err == errThreshold {
+		err = ErrNoRate
+	}
+	return x, err
+}
 
 func newRatesEx(rates []Rate) Interface {
 	e := new(ratesEx)
 	// Use index to iterate over the slice not to copy structs.
 	for i := range rates {
-		// TODO: panic when multiple rates have same thresholds?
 		rate := &rates[i]
+		// A rate without a threshold applies to any amount.
+		if rate.Threshold == nil {
+			rate.Threshold = nilThreshold
+		}
 		e.buy = append(e.buy, rate)
 		e.sell = append(e.sell, rate)
 		e.rates = append(e.rates, rate)
@@ -131,17 +287,67 @@ type ratesEx struct {
 	rates []*Rate
 }
 
+// sortRates sorts rates by thresholds in descending order. Of rates with
+// equal thresholds the first one is used, NewTiered rejects such tables.
 func (e *ratesEx) sortRates() {
-	sort.Slice(e.buy, func(i, j int) bool {
-		return e.buy[i].Threshold.Buy() > e.buy[j].Threshold.Buy()
+	sort.SliceStable(e.buy, func(i, j int) bool {
+		return e.buy[i].buyThreshold().Cmp(e.buy[j].buyThreshold()) > 0
 	})
-	sort.Slice(e.sell, func(i, j int) bool {
-		return e.sell[i].Threshold.Sell() > e.sell[j].Threshold.Sell()
+	sort.SliceStable(e.sell, func(i, j int) bool {
+		return e.sell[i].sellThreshold().Cmp(e.sell[j].sellThreshold()) > 0
 	})
 }
 
-func (e *ratesEx) Buy(x float64) (float64, error)  { return exchange(x, e.buy, chooseBuy) }
-func (e *ratesEx) Sell(x float64) (float64, error) { return exchange(x, e.sell, chooseSell) }
+func (e *ratesEx) Buy(x float64) (float64, error)    { return DecimalFunc(e.BuyDecimal).Float()(x) }
+func (e *ratesEx) Sell(x float64) (float64, error)   { return DecimalFunc(e.SellDecimal).Float()(x) }
+func (e *ratesEx) BuyFor(y float64) (float64, error) { return DecimalFunc(e.BuyForDecimal).Float()(y) }
+func (e *ratesEx) SellFor(y float64) (float64, error) {
+	return DecimalFunc(e.SellForDecimal).Float()(y)
+}
+
+func (e *ratesEx) BuyDecimal(x money.Decimal) (money.Decimal, error) {
+	return exchange(x, e.buy, chooseBuy)
+}
+
+func (e *ratesEx) SellDecimal(x money.Decimal) (money.Decimal, error) {
+	return exchange(x, e.sell, chooseSell)
+}
+
+func (e *ratesEx) BuyForDecimal(y money.Decimal) (money.Decimal, error) {
+	return reverse(y, e.buy, func(r *Rate) (money.Decimal, money.Decimal) { return r.BuyRate(), r.buyThreshold() })
+}
+
+func (e *ratesEx) SellForDecimal(y money.Decimal) (money.Decimal, error) {
+	return reverse(y, e.sell, func(r *Rate) (money.Decimal, money.Decimal) { return r.SellRate(), r.sellThreshold() })
+}
+
+// reverse returns the minimal amount exchanged into at least y. rates are
+// sorted by thresholds in descending order, so a rate applies to amounts
+// from its threshold up to a threshold of the previous rate. Larger amounts
+// may get better rates, so a larger threshold may need a smaller amount.
+func reverse(y money.Decimal, rates []*Rate, f func(*Rate) (rate, threshold money.Decimal)) (money.Decimal, error) {
+	if y.Sign() < 0 {
+		return money.Decimal{}, ErrNegativeAmount
+	}
+	var x, upper *money.Decimal
+	for _, r := range rates {
+		rate, threshold := f(r)
+		if v, err := solve(y, rate, threshold, upper); err == nil && (x == nil || v.Cmp(*x) < 0) {
+			x = &v
+		}
+		upper = &threshold
+	}
+	if x == nil {
+		return money.Decimal{}, ErrNoRate
+	}
+	return *x, nil
+}
+
+func (e *ratesEx) Tiers() (buy, sell Tiers) {
+	buy = makeTiers(e.buy, (*Rate).BuyRate, (*Rate).buyThreshold)
+	sell = makeTiers(e.sell, (*Rate).SellRate, (*Rate).sellThreshold)
+	return
+}
 
 func (e *ratesEx) Rates() []Rate {
 	v := make([]Rate, len(e.rates))
@@ -151,14 +357,14 @@ func (e *ratesEx) Rates() []Rate {
 	return v
 }
 
-type chooseFunc func(*Rate) Func
+type chooseFunc func(*Rate) DecimalFunc
 
 var (
-	chooseBuy  chooseFunc = func(r *Rate) Func { return r.doBuy }
-	chooseSell            = func(r *Rate) Func { return r.doSell }
+	chooseBuy  chooseFunc = func(r *Rate) DecimalFunc { return r.doBuy }
+	chooseSell            = func(r *Rate) DecimalFunc { return r.doSell }
 )
 
-func exchange(x float64, rates []*Rate, chooseFunc chooseFunc) (y float64, err error) {
+func exchange(x money.Decimal, rates []*Rate, chooseFunc chooseFunc) (y money.Decimal, err error) {
 	for _, rate := range rates {
 		y, err = chooseFunc(rate)(x)
 		if err == errThreshold {
@@ -166,7 +372,7 @@ func exchange(x float64, rates []*Rate, chooseFunc chooseFunc) (y float64, err e
 		}
 		return
 	}
-	y, err = 0, ErrNoRate
+	y, err = money.Decimal{}, ErrNoRate
 	return
 }
 
@@ -177,3 +383,86 @@ func Invert(v Interface) Interface {
 	}
 	return New(rates...)
 }
+
+// Compose returns an Interface exchanging through a and then b, e.g. USD to
+// RUB and then RUB to EUR.
+func Compose(a, b Interface) Interface {
+	return &composed{a: a, b: b}
+}
+
+type composed struct{ a, b Interface }
+
+func (e *composed) Buy(x float64) (float64, error)    { return DecimalFunc(e.BuyDecimal).Float()(x) }
+func (e *composed) Sell(x float64) (float64, error)   { return DecimalFunc(e.SellDecimal).Float()(x) }
+func (e *composed) BuyFor(y float64) (float64, error) { return DecimalFunc(e.BuyForDecimal).Float()(y) }
+func (e *composed) SellFor(y float64) (float64, error) {
+	return DecimalFunc(e.SellForDecimal).Float()(y)
+}
+
+func (e *composed) BuyDecimal(x money.Decimal) (money.Decimal, error) {
+	y, err := e.a.BuyDecimal(x)
+	if err != nil {
+		return money.Decimal{}, err
+	}
+	return e.b.BuyDecimal(y)
+}
+
+func (e *composed) SellDecimal(x money.Decimal) (money.Decimal, error) {
+	y, err := e.a.SellDecimal(x)
+	if err != nil {
+		return money.Decimal{}, err
+	}
+	return e.b.SellDecimal(y)
+}
+
+// BuyForDecimal returns an amount of a exchanged into the amount
+// b.BuyForDecimal needs.
+func (e *composed) BuyForDecimal(y money.Decimal) (money.Decimal, error) {
+	x, err := e.b.BuyForDecimal(y)
+	if err != nil {
+		return money.Decimal{}, err
+	}
+	return e.a.BuyForDecimal(x)
+}
+
+func (e *composed) SellForDecimal(y money.Decimal) (money.Decimal, error) {
+	x, err := e.b.SellForDecimal(y)
+	if err != nil {
+		return money.Decimal{}, err
+	}
+	return e.a.SellForDecimal(x)
+}
+
+// Rates returns products of rates of a and b. Thresholds of b are converted
+// into units of a.
+func (e *composed) Rates() []Rate {
+	var v []Rate
+	for _, ra := range e.a.Rates() {
+		for _, rb := range e.b.Rates() {
+			v = append(v, composeRates(&ra, &rb))
+		}
+	}
+	return v
+}
+
+// Tiers returns tiers of Rates.
+func (e *composed) Tiers() (buy, sell Tiers) {
+	return newRatesEx(e.Rates()).Tiers()
+}
+
+func composeRates(a, b *Rate) Rate {
+	ab, as := a.buyThreshold(), a.sellThreshold()
+	bb, bs := quo(b.buyThreshold(), a.BuyRate()), quo(b.sellThreshold(), a.SellRate())
+	return NewRate(
+		a.BuyRate().Mul(b.BuyRate()),
+		a.SellRate().Mul(b.SellRate()),
+		NewDecimalThreshold(maxDecimal(ab, bb), maxDecimal(as, bs)),
+	)
+}
+
+func maxDecimal(a, b money.Decimal) money.Decimal {
+	if a.Cmp(b) < 0 {
+		return b
+	}
+	return a
+}
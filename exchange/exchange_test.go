@@ -3,6 +3,8 @@ package exchange
 import (
 	"fmt"
 	"testing"
+
+	"github.com/koorgoo/vtb24/money"
 )
 
 func TestNew_panicWithoutRates(t *testing.T) {
@@ -95,3 +97,142 @@ func testFunc(t *testing.T, exchange Func, table Table) {
 		}
 	}
 }
+
+func TestCompose(t *testing.T) {
+	// USD to RUB and then RUB to EUR.
+	a := New(Rate{Buy: 60, Sell: 64})
+	b := Invert(New(
+		Rate{Buy: 64, Sell: 80, Threshold: NewThreshold(0, 0)},
+		Rate{Buy: 128, Sell: 80, Threshold: NewThreshold(100, 100)},
+	))
+	e := Compose(a, b)
+
+	test(t, e, Table{
+		-1: {0, ErrNegativeAmount},
+		// 100 USD = 6000 RUB = 6000/80 EUR.
+		100: {75, nil},
+	}, Table{
+		-1: {0, ErrNegativeAmount},
+		// 100 USD cost 6400 RUB = 6400/64 EUR.
+		100: {100, nil},
+		// 19200 RUB are enough to buy at least 100 EUR at a better rate.
+		300: {150, nil},
+	})
+
+	if n := len(e.Rates()); n != 2 {
+		t.Fatalf("want 2 rates, got %d", n)
+	}
+}
+
+var ReverseTests = []struct {
+	Rates   []Rate
+	BuyFor  Table
+	SellFor Table
+}{
+	{
+		Rates: []Rate{{Buy: 2, Sell: 4}},
+		BuyFor: Table{
+			-1: {0, ErrNegativeAmount},
+			0:  {0, nil},
+			5:  {2.5, nil},
+		},
+		SellFor: Table{
+			10: {2.5, nil},
+		},
+	},
+	{
+		Rates: []Rate{
+			{Buy: 2, Sell: 3, Threshold: NewThreshold(10, 10)},
+			{Buy: 3, Sell: 2, Threshold: NewThreshold(20, 20)},
+		},
+		BuyFor: Table{
+			-1: {0, ErrNegativeAmount},
+			// The minimal amount of the first threshold.
+			4:  {10, nil},
+			30: {15, nil},
+			// 20 exchanged at the better rate give 60.
+			45: {20, nil},
+			90: {30, nil},
+		},
+		SellFor: Table{
+			// 13.33 at the first rate is less than 20 at the second one.
+			40: {40.0 / 3, nil},
+			// 60/3 is not less than the second threshold.
+			60:  {30, nil},
+			100: {50, nil},
+		},
+	},
+	{
+		Rates: []Rate{{Buy: 0, Sell: 0}},
+		BuyFor: Table{
+			1: {0, ErrNoRate},
+		},
+		SellFor: Table{
+			1: {0, ErrNoRate},
+		},
+	},
+}
+
+func TestReverse(t *testing.T) {
+	for _, tt := range ReverseTests {
+		e := New(tt.Rates...)
+		t.Run(fmt.Sprintf("%+v", tt.Rates), func(t *testing.T) {
+			t.Run("buy", func(t *testing.T) { testFunc(t, e.BuyFor, tt.BuyFor) })
+			t.Run("sell", func(t *testing.T) { testFunc(t, e.SellFor, tt.SellFor) })
+		})
+	}
+}
+
+func TestCompose_reverse(t *testing.T) {
+	// USD to RUB and then RUB to EUR.
+	e := Compose(New(Rate{Buy: 60, Sell: 64}), New(Rate{Buy: 1.0 / 80, Sell: 1.0 / 64}))
+	// 75 EUR cost 6000 RUB = 100 USD.
+	if n, err := e.BuyFor(75); err != nil || n != 100 {
+		t.Errorf("want 100, got %v, %v", n, err)
+	}
+}
+
+func TestInvert_exact(t *testing.T) {
+	// 1/3 has no exact binary or decimal representation, but inverting it
+	// twice must give the rate back.
+	e := Invert(Invert(New(NewRate(money.New(3, 0), money.New(3, 0), nil))))
+	n, err := e.BuyDecimal(money.New(1, 0))
+	if err != nil || n.Cmp(money.New(3, 0)) != 0 {
+		t.Errorf("want 3, got %v, %v", n, err)
+	}
+	// Amounts at 0.1 and 0.2 add up exactly, unlike floats.
+	e = New(NewRate(money.New(1, 1), money.New(2, 1), nil))
+	b, _ := e.BuyDecimal(money.New(1000000, 0))
+	s, _ := e.SellDecimal(money.New(1000000, 0))
+	if sum := b.Add(s); sum.Cmp(money.New(300000, 0)) != 0 {
+		t.Errorf("want 300000, got %v", sum)
+	}
+}
+
+func TestReverse_exact(t *testing.T) {
+	e := New(NewRate(money.New(3, 0), money.New(3, 0), nil))
+	x, err := e.BuyForDecimal(money.New(1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1/3 is exchanged into exactly 1.
+	if y, _ := e.BuyDecimal(x); y.Cmp(money.New(1, 0)) != 0 {
+		t.Errorf("want 1, got %v", y)
+	}
+	if s := money.RoundMinor(x, "RUB", money.Ceil).String(); s != "0.34" {
+		t.Errorf("want 0.34, got %s", s)
+	}
+}
+
+func TestCompose_thresholds(t *testing.T) {
+	// 1000 RUB of the second leg are 10 USD of the first one.
+	a := New(NewRate(money.New(100, 0), money.New(100, 0), nil))
+	b := New(
+		NewRate(money.New(1, 0), money.New(1, 0), NewDecimalThreshold(money.New(0, 0), money.New(0, 0))),
+		NewRate(money.New(2, 0), money.New(2, 0), NewDecimalThreshold(money.New(1000, 0), money.New(1000, 0))),
+	)
+	buy, _ := Compose(a, b).Tiers()
+	if len(buy) != 2 || buy[1].From.Cmp(money.New(10, 0)) != 0 || buy[1].Rate.Cmp(money.New(200, 0)) != 0 {
+		t.Errorf("want 200 from 10, got %v", buy)
+	}
+}
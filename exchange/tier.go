@@ -0,0 +1,148 @@
+package exchange
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/koorgoo/vtb24/money"
+)
+
+var (
+	ErrNoTiers       = errors.New("exchange: no tiers")
+	ErrTierRange     = errors.New("exchange: tier ends before it starts")
+	ErrTierDuplicate = errors.New("exchange: duplicate tier threshold")
+	ErrTierOverlap   = errors.New("exchange: tiers overlap")
+	ErrTierGap       = errors.New("exchange: gap between tiers")
+)
+
+// Tier is a rate for amounts from From up to To. Zero To means no upper
+// bound.
+type Tier struct {
+	From, To money.Decimal
+	Rate     money.Decimal
+}
+
+func (t *Tier) bounded() bool { return t.To.Sign() != 0 }
+
+// Tiers is a tier table of one side of an exchange.
+type Tiers []Tier
+
+// Chain returns t sorted by From with To of every tier set to From of the
+// next one. The last tier has no upper bound.
+func (t Tiers) Chain() Tiers {
+	v := t.sorted()
+	for i := range v {
+		v[i].To = money.Decimal{}
+		if i+1 < len(v) {
+			v[i].To = v[i+1].From
+		}
+	}
+	return v
+}
+
+func (t Tiers) sorted() Tiers {
+	v := append(Tiers(nil), t...)
+	sort.SliceStable(v, func(i, j int) bool { return v[i].From.Cmp(v[j].From) < 0 })
+	return v
+}
+
+// Validate checks that t covers all amounts from its lowest threshold once.
+// Only the last tier may have no upper bound.
+func (t Tiers) Validate() error {
+	if len(t) == 0 {
+		return ErrNoTiers
+	}
+	v := t.sorted()
+	for i, tier := range v {
+		switch {
+		case tier.From.Sign() < 0:
+			return ErrNegativeAmount
+		case tier.bounded() && tier.To.Cmp(tier.From) <= 0:
+			return ErrTierRange
+		case i > 0 && v[i-1].From.Cmp(tier.From) == 0:
+			return ErrTierDuplicate
+		}
+	}
+	for i, tier := range v[:len(v)-1] {
+		switch next := v[i+1]; {
+		case !tier.bounded() || tier.To.Cmp(next.From) > 0:
+			return ErrTierOverlap
+		case tier.To.Cmp(next.From) < 0:
+			return ErrTierGap
+		}
+	}
+	if v[len(v)-1].bounded() {
+		return ErrTierGap
+	}
+	return nil
+}
+
+// Find returns a tier of amount x.
+func (t Tiers) Find(x money.Decimal) (Tier, bool) {
+	for _, tier := range t {
+		if x.Cmp(tier.From) >= 0 && (!tier.bounded() || x.Cmp(tier.To) < 0) {
+			return tier, true
+		}
+	}
+	return Tier{}, false
+}
+
+// NewTiered returns an Interface with separate buy and sell tier tables. It
+// fails when a table is invalid.
+func NewTiered(buy, sell Tiers) (Interface, error) {
+	if err := buy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := sell.Validate(); err != nil {
+		return nil, err
+	}
+	buy, sell = buy.sorted(), sell.sorted()
+
+	// Rates pair tiers of both sides. Tables of different sizes leave
+	// missing tiers of a side to the last one.
+	n := len(buy)
+	if len(sell) > n {
+		n = len(sell)
+	}
+	e := new(ratesEx)
+	for i := 0; i < n; i++ {
+		b, s := buy[minInt(i, len(buy)-1)], sell[minInt(i, len(sell)-1)]
+		r := NewRate(b.Rate, s.Rate, NewDecimalThreshold(b.From, s.From))
+		rate := &r
+		e.rates = append(e.rates, rate)
+		if i < len(buy) {
+			e.buy = append(e.buy, rate)
+		}
+		if i < len(sell) {
+			e.sell = append(e.sell, rate)
+		}
+	}
+	e.sortRates()
+	return e, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// makeTiers returns tiers of rates sorted by thresholds in descending order.
+// Of equal thresholds the first rate is used as exchange does.
+func makeTiers(rates []*Rate, rate func(*Rate) money.Decimal, threshold func(*Rate) money.Decimal) Tiers {
+	var v Tiers
+	var upper money.Decimal
+	for _, r := range rates {
+		from := threshold(r)
+		if len(v) > 0 && v[len(v)-1].From.Cmp(from) == 0 {
+			continue
+		}
+		v = append(v, Tier{From: from, To: upper, Rate: rate(r)})
+		upper = from
+	}
+	for i, j := 0, len(v)-1; i < j; i, j = i+1, j-1 {
+		v[i], v[j] = v[j], v[i]
+	}
+	return v
+}
@@ -0,0 +1,111 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/koorgoo/vtb24/money"
+)
+
+func tier(from, to, rate int64) Tier {
+	return Tier{From: money.New(from, 0), To: money.New(to, 0), Rate: money.New(rate, 0)}
+}
+
+func equalTiers(a, b Tiers) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].From.Cmp(b[i].From) != 0 || a[i].To.Cmp(b[i].To) != 0 || a[i].Rate.Cmp(b[i].Rate) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTiers_Validate(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Tiers Tiers
+		Err   error
+	}{
+		{"empty", nil, ErrNoTiers},
+		{"single", Tiers{tier(0, 0, 1)}, nil},
+		{"chained", Tiers{tier(0, 1000, 1), tier(1000, 0, 2)}, nil},
+		{"unsorted", Tiers{tier(1000, 0, 2), tier(0, 1000, 1)}, nil},
+		{"from threshold", Tiers{tier(10, 20, 1), tier(20, 0, 2)}, nil},
+		{"negative", Tiers{tier(-1, 0, 1)}, ErrNegativeAmount},
+		{"range", Tiers{tier(0, 1000, 1), tier(1000, 500, 2)}, ErrTierRange},
+		{"duplicate", Tiers{tier(0, 1000, 1), tier(1000, 0, 2), tier(1000, 0, 3)}, ErrTierDuplicate},
+		{"overlap", Tiers{tier(0, 2000, 1), tier(1000, 0, 2)}, ErrTierOverlap},
+		{"unbounded", Tiers{tier(0, 0, 1), tier(1000, 0, 2)}, ErrTierOverlap},
+		{"gap", Tiers{tier(0, 500, 1), tier(1000, 0, 2)}, ErrTierGap},
+		{"bounded", Tiers{tier(0, 1000, 1)}, ErrTierGap},
+	}
+	for _, tt := range tests {
+		if err := tt.Tiers.Validate(); err != tt.Err {
+			t.Errorf("%s: want %v, got %v", tt.Name, tt.Err, err)
+		}
+	}
+}
+
+func TestTiers_Chain(t *testing.T) {
+	v := Tiers{tier(1000, 0, 2), tier(0, 0, 1), tier(5000, 0, 3)}.Chain()
+	want := Tiers{tier(0, 1000, 1), tier(1000, 5000, 2), tier(5000, 0, 3)}
+	if !equalTiers(v, want) {
+		t.Errorf("want %v, got %v", want, v)
+	}
+}
+
+func TestNewTiered(t *testing.T) {
+	// The bank sells at a better rate from 500, but buys from 1000 only.
+	e, err := NewTiered(
+		Tiers{tier(0, 1000, 2), tier(1000, 0, 3)},
+		Tiers{tier(0, 500, 6), tier(500, 0, 5)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test(t, e, Table{
+		10:   {20, nil},
+		500:  {1000, nil},
+		1000: {3000, nil},
+	}, Table{
+		10:   {60, nil},
+		500:  {2500, nil},
+		1000: {5000, nil},
+	})
+
+	buy, sell := e.Tiers()
+	if want := (Tiers{tier(0, 1000, 2), tier(1000, 0, 3)}); !equalTiers(buy, want) {
+		t.Errorf("buy: want %v, got %v", want, buy)
+	}
+	if want := (Tiers{tier(0, 500, 6), tier(500, 0, 5)}); !equalTiers(sell, want) {
+		t.Errorf("sell: want %v, got %v", want, sell)
+	}
+}
+
+func TestNewTiered_invalid(t *testing.T) {
+	valid := Tiers{tier(0, 0, 1)}
+	if _, err := NewTiered(Tiers{tier(0, 0, 1), tier(0, 0, 2)}, valid); err != ErrTierDuplicate {
+		t.Errorf("buy: want %v, got %v", ErrTierDuplicate, err)
+	}
+	if _, err := NewTiered(valid, nil); err != ErrNoTiers {
+		t.Errorf("sell: want %v, got %v", ErrNoTiers, err)
+	}
+}
+
+func TestNew_tiers(t *testing.T) {
+	// Of equal thresholds the first rate is used.
+	e := New(
+		NewRate(money.New(2, 0), money.New(3, 0), NewThreshold(0, 0)),
+		NewRate(money.New(4, 0), money.New(5, 0), NewThreshold(10, 10)),
+		NewRate(money.New(6, 0), money.New(7, 0), NewThreshold(10, 10)),
+	)
+	if n, err := e.Buy(10); err != nil || n != 40 {
+		t.Errorf("want 40, got %v, %v", n, err)
+	}
+	buy, _ := e.Tiers()
+	if want := (Tiers{tier(0, 10, 2), tier(10, 0, 4)}); !equalTiers(buy, want) {
+		t.Errorf("want %v, got %v", want, buy)
+	}
+}
@@ -0,0 +1,222 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/koorgoo/vtb24/bank"
+)
+
+// File is a Store keeping records in a file as JSON lines. A record is
+// appended only when rates of an exchange change, so a record holds until
+// the next record of the exchange. Records are kept in memory as well.
+type File struct {
+	filename  string
+	retention time.Duration
+
+	mu        sync.Mutex
+	f         *os.File
+	records   []Record
+	compacted time.Time
+}
+
+var _ Store = (*File)(nil)
+
+// Open opens or creates a history file. Records older than retention are
+// removed except the last record of every exchange. Zero retention keeps
+// all records.
+func Open(filename string, retention time.Duration) (*File, error) {
+	records, err := readRecords(filename)
+	if err != nil {
+		return nil, fmt.Errorf("history: %s", err)
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("history: %s", err)
+	}
+	s := &File{filename: filename, retention: retention, f: f, records: records}
+	s.compacted = s.compactedAt()
+	return s, nil
+}
+
+// compactedAt estimates time of the last compaction of loaded records, so
+// that the file is not rewritten on every start. The file is compacted
+// once its oldest removable record is two retention periods old.
+func (s *File) compactedAt() time.Time {
+	if len(s.records) == 0 {
+		return time.Time{}
+	}
+	last := s.last()
+	for i, r := range s.records {
+		if last[r.key()] != i {
+			return r.Time.Add(s.retention)
+		}
+	}
+	// No record is removable until rates change.
+	return s.records[len(s.records)-1].Time
+}
+
+func readRecords(filename string) ([]Record, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var v []Record
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var r Record
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", filename, err)
+		}
+		v = append(v, r)
+	}
+	sort.SliceStable(v, func(i, j int) bool {
+		return v[i].Time.Before(v[j].Time)
+	})
+	return v, nil
+}
+
+func (s *File) Append(t time.Time, ex []bank.Ex) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := s.last()
+	w := bufio.NewWriter(s.f)
+	enc := json.NewEncoder(w)
+	var added []Record
+	for _, r := range makeRecords(t, ex) {
+		if i, ok := last[r.key()]; ok && equalRates(s.records[i].Rates, r.Rates) {
+			continue
+		}
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("history: %s", err)
+		}
+		added = append(added, r)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("history: %s", err)
+	}
+	s.insert(added)
+
+	// Rewriting the file once per retention keeps it under two retention
+	// periods.
+	if s.retention > 0 && t.Sub(s.compacted) >= s.retention {
+		return s.compact(t.Add(-s.retention))
+	}
+	return nil
+}
+
+// insert adds records keeping them ordered by time.
+func (s *File) insert(v []Record) {
+	for _, r := range v {
+		i := sort.Search(len(s.records), func(i int) bool {
+			return s.records[i].Time.After(r.Time)
+		})
+		s.records = append(s.records, Record{})
+		copy(s.records[i+1:], s.records[i:])
+		s.records[i] = r
+	}
+}
+
+// last returns indexes of the latest records of exchanges.
+func (s *File) last() map[key]int {
+	m := map[key]int{}
+	for i := range s.records {
+		m[s.records[i].key()] = i
+	}
+	return m
+}
+
+// compact removes records before cutoff except the last record of every
+// exchange and rewrites the file atomically.
+func (s *File) compact(cutoff time.Time) error {
+	last := s.last()
+	var v []Record
+	for i, r := range s.records {
+		if !r.Time.Before(cutoff) || last[r.key()] == i {
+			v = append(v, r)
+		}
+	}
+
+	tmp := s.filename + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("history: %s", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range v {
+		if err = enc.Encode(r); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.filename)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("history: %s", err)
+	}
+
+	// f is at its end, so records are appended to the new file.
+	s.f.Close()
+	s.f = f
+	s.records = v
+	s.compacted = cutoff.Add(s.retention)
+	return nil
+}
+
+func (s *File) Query(q Query) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A record holds until the next record of the exchange, so the last
+	// record at or before q.From holds at q.From.
+	prev := map[key]int{}
+	if !q.From.IsZero() {
+		for i := range s.records {
+			r := &s.records[i]
+			if r.Time.After(q.From) {
+				break
+			}
+			if q.matchExchange(r) {
+				prev[r.key()] = i
+			}
+		}
+	}
+
+	var v []Record
+	for i := range s.records {
+		r := &s.records[i]
+		j, ok := prev[r.key()]
+		if held := ok && j == i && (q.To.IsZero() || !q.To.Before(q.From)); held || q.match(r) {
+			v = append(v, *r)
+		}
+	}
+	return v, nil
+}
+
+func (s *File) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
@@ -0,0 +1,185 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/money"
+)
+
+func testEx(usd, eur int64) []bank.Ex {
+	return bank.ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: value(usd), Sell: value(usd + 2)},
+			{CurrencyGroupAbbr: api.GroupCash, CurrencyAbbr: api.EUR, Buy: value(eur), Sell: value(eur + 3)},
+		},
+	}, time.Time{})
+}
+
+func value(n int64) api.ItemValue { return api.ItemValue(money.New(n, 0)) }
+
+var t0 = time.Date(2017, time.October, 1, 12, 0, 0, 0, time.UTC)
+
+func TestFile(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "history.jsonl"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	t1 := t0.Add(time.Hour)
+	if err = s.Append(t1, testEx(57, 67)); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Append(t0, testEx(58, 68)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name  string
+		Query Query
+		Times []time.Time
+	}{
+		{"all usd", Query{Src: api.USD}, []time.Time{t0, t1}},
+		{"group", Query{Group: api.GroupCash}, []time.Time{t0, t1}},
+		{"from", Query{Src: api.USD, From: t1}, []time.Time{t1}},
+		{"held from", Query{Src: api.USD, From: t0.Add(time.Minute)}, []time.Time{t0, t1}},
+		{"held from and to", Query{Src: api.USD, From: t0.Add(time.Minute), To: t0.Add(2 * time.Minute)}, []time.Time{t0}},
+		{"after all", Query{Group: api.GroupCash, From: t1.Add(time.Hour)}, []time.Time{t1}},
+		{"to", Query{Src: api.USD, To: t0}, []time.Time{t0}},
+		{"no match", Query{Src: api.GBP}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			v, err := s.Query(tt.Query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(v) != len(tt.Times) {
+				t.Fatalf("want %d records, got %d", len(tt.Times), len(v))
+			}
+			for i, r := range v {
+				if !r.Time.Equal(tt.Times[i]) {
+					t.Errorf("%d: want %s, got %s", i, tt.Times[i], r.Time)
+				}
+			}
+		})
+	}
+}
+
+func times(t *testing.T, s Store, q Query) []time.Time {
+	v, err := s.Query(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a []time.Time
+	for _, r := range v {
+		a = append(a, r.Time)
+	}
+	return a
+}
+
+func TestFile_unchanged(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.jsonl")
+	s, err := Open(filename, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Append(t0, testEx(57, 67)); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Append(t0.Add(time.Hour), testEx(57, 68)); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// Last records are restored on open.
+	s, err = Open(filename, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err = s.Append(t0.Add(2*time.Hour), testEx(57, 68)); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := times(t, s, Query{Src: api.USD}); !reflect.DeepEqual(v, []time.Time{t0}) {
+		t.Errorf("usd: want only %s, got %v", t0, v)
+	}
+	if v := times(t, s, Query{Src: api.EUR}); !reflect.DeepEqual(v, []time.Time{t0, t0.Add(time.Hour)}) {
+		t.Errorf("eur: want 2 records, got %v", v)
+	}
+}
+
+func TestFile_retention(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.jsonl")
+	s, err := Open(filename, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// USD changes every day, EUR never changes after the first day.
+	for i := 0; i < 5; i++ {
+		if err = s.Append(t0.AddDate(0, 0, i), testEx(int64(57+i), 67)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := times(t, s, Query{Src: api.USD}); len(v) > 2 || !v[len(v)-1].Equal(t0.AddDate(0, 0, 4)) {
+		t.Errorf("usd: want up to 2 last records, got %v", v)
+	}
+	if v := times(t, s, Query{Src: api.EUR}); !reflect.DeepEqual(v, []time.Time{t0}) {
+		t.Errorf("eur: want the last record kept, got %v", v)
+	}
+
+	// The file is compacted as well.
+	s.Close()
+	s, err = Open(filename, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if v := times(t, s, Query{}); len(v) > 3 {
+		t.Errorf("want up to 3 records, got %v", v)
+	}
+}
+
+func TestFile_reopenNotCompacted(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.jsonl")
+	s, err := Open(filename, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = s.Append(t0.Add(time.Duration(i)*time.Hour), testEx(int64(57+i), 67)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+
+	s, err = Open(filename, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	fi, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file is appended to rather than rewritten within a retention.
+	if err = s.Append(t0.Add(3*time.Hour), testEx(60, 67)); err != nil {
+		t.Fatal(err)
+	}
+	fi2, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(fi, fi2) {
+		t.Error("want the file appended, got it rewritten")
+	}
+}
@@ -0,0 +1,107 @@
+// Package history keeps snapshots of exchange rates.
+package history
+
+import (
+	"time"
+
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/exchange"
+)
+
+// Store appends snapshots of rates and queries them back.
+type Store interface {
+	// Append saves rates fetched at time t. Unchanged rates of an exchange
+	// may be skipped.
+	Append(t time.Time, ex []bank.Ex) error
+	// Query returns records matching q ordered by time. A record of an
+	// exchange before q.From is returned as well when it holds at q.From.
+	Query(q Query) ([]Record, error)
+	Close() error
+}
+
+// Query filters records. Empty fields match any value.
+type Query struct {
+	Src   string
+	Dst   string
+	Group string
+	From  time.Time
+	To    time.Time
+}
+
+func (q *Query) match(r *Record) bool {
+	return q.matchExchange(r) && q.matchTime(r)
+}
+
+func (q *Query) matchExchange(r *Record) bool {
+	switch {
+	case q.Src != "" && q.Src != r.Src:
+		return false
+	case q.Dst != "" && q.Dst != r.Dst:
+		return false
+	case q.Group != "" && q.Group != r.Group:
+		return false
+	}
+	return true
+}
+
+func (q *Query) matchTime(r *Record) bool {
+	switch {
+	case !q.From.IsZero() && r.Time.Before(q.From):
+		return false
+	case !q.To.IsZero() && r.Time.After(q.To):
+		return false
+	}
+	return true
+}
+
+// Record is a quote of a single exchange at some moment.
+type Record struct {
+	Time  time.Time `json:"time"`
+	Src   string    `json:"src"`
+	Dst   string    `json:"dst"`
+	Group string    `json:"group"`
+	Rates []Rate    `json:"rates"`
+}
+
+type key struct{ src, dst, group string }
+
+func (r *Record) key() key { return key{r.Src, r.Dst, r.Group} }
+
+type Rate struct {
+	Buy           float64 `json:"buy"`
+	Sell          float64 `json:"sell"`
+	BuyThreshold  float64 `json:"buy_threshold"`
+	SellThreshold float64 `json:"sell_threshold"`
+}
+
+func makeRecords(t time.Time, ex []bank.Ex) []Record {
+	v := make([]Record, len(ex))
+	for i, e := range ex {
+		v[i] = Record{Time: t, Src: e.Src(), Dst: e.Dst(), Group: e.Group()}
+		for _, r := range e.Rates() {
+			v[i].Rates = append(v[i].Rates, makeRate(r))
+		}
+	}
+	return v
+}
+
+func makeRate(r exchange.Rate) Rate {
+	v := Rate{Buy: r.Buy, Sell: r.Sell}
+	if r.Threshold != nil {
+		v.BuyThreshold = r.Threshold.Buy()
+		v.SellThreshold = r.Threshold.Sell()
+	}
+	return v
+}
+
+func equalRates(a, b []Rate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
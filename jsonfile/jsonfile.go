@@ -0,0 +1,36 @@
+// Package jsonfile reads and writes values as JSON files.
+package jsonfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// Load decodes filename into v. It returns an error satisfying os.IsNotExist
+// when the file is missing, so stores may start empty.
+func Load(filename string, v interface{}) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+	return nil
+}
+
+// Save writes v to filename atomically: a reader sees either the old file or
+// the new one.
+func Save(filename string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
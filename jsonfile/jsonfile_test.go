@@ -0,0 +1,37 @@
+package jsonfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "v.json")
+
+	var v map[string]int
+	if err := Load(filename, &v); !os.IsNotExist(err) {
+		t.Fatalf("want not exist, got %v", err)
+	}
+	if err := Save(filename, map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(filename, &v); err != nil || v["a"] != 1 {
+		t.Fatalf("want a=1, got %v, %v", v, err)
+	}
+	if _, err := os.Stat(filename + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("want no temporary file, got %v", err)
+	}
+}
+
+func TestLoad_invalid(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "v.json")
+	if err := ioutil.WriteFile(filename, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]int
+	if err := Load(filename, &v); err == nil || os.IsNotExist(err) {
+		t.Errorf("want syntax error, got %v", err)
+	}
+}
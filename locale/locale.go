@@ -0,0 +1,118 @@
+// Package locale translates texts shown to users.
+package locale
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Supported languages.
+const (
+	RU = "ru"
+	EN = "en"
+)
+
+// Default is a language of chats without a known language. Messages missing
+// in other languages fall back to it.
+const Default = RU
+
+// Languages are codes of supported languages.
+var Languages = []string{RU, EN}
+
+// Match returns a supported language of a Telegram language code like
+// "en-US". It returns an empty string for unsupported languages.
+func Match(code string) string {
+	code = strings.ToLower(code)
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	if _, ok := catalog[code]; ok {
+		return code
+	}
+	return ""
+}
+
+// Printer formats messages in a language.
+type Printer struct {
+	lang string
+}
+
+// New returns a Printer of lang. Unsupported languages fall back to
+// Default.
+func New(lang string) *Printer {
+	if _, ok := catalog[lang]; !ok {
+		lang = Default
+	}
+	return &Printer{lang: lang}
+}
+
+// Lang returns a language of p.
+func (p *Printer) Lang() string { return p.lang }
+
+// Sprintf formats a message with args like fmt.Sprintf does.
+func (p *Printer) Sprintf(key string, args ...interface{}) string {
+	return fmt.Sprintf(p.text(key), args...)
+}
+
+// Plural formats a message with plural forms for n. Forms are separated by
+// "|" and chosen by rules of the language.
+func (p *Printer) Plural(key string, n int) string {
+	forms := strings.Split(p.text(key), "|")
+	i := pluralRules[p.lang](n)
+	if i >= len(forms) {
+		i = len(forms) - 1
+	}
+	return fmt.Sprintf(forms[i], n)
+}
+
+// Group returns a text of a currency group.
+func (p *Printer) Group(group string) string {
+	if s, ok := p.lookup(groupPrefix + group); ok {
+		return s
+	}
+	return fmt.Sprintf("unknown group %q", group)
+}
+
+// GroupButton returns a short text of a currency group for buttons.
+func (p *Printer) GroupButton(group string) string {
+	if s, ok := p.lookup(groupButtonPrefix + group); ok {
+		return s
+	}
+	return p.Group(group)
+}
+
+func (p *Printer) text(key string) string {
+	if s, ok := p.lookup(key); ok {
+		return s
+	}
+	return key
+}
+
+func (p *Printer) lookup(key string) (string, bool) {
+	if s, ok := catalog[p.lang][key]; ok {
+		return s, true
+	}
+	s, ok := catalog[Default][key]
+	return s, ok
+}
+
+var pluralRules = map[string]func(n int) int{
+	// One, few, many: 1 минута, 2 минуты, 5 минут.
+	RU: func(n int) int {
+		switch n10, n100 := n%10, n%100; {
+		case n10 == 1 && n100 != 11:
+			return 0
+		case n10 >= 2 && n10 <= 4 && (n100 < 12 || n100 > 14):
+			return 1
+		default:
+			return 2
+		}
+	},
+	// One, other: 1 minute, 2 minutes.
+	EN: func(n int) int {
+		if n == 1 {
+			return 0
+		}
+		return 1
+	},
+}
@@ -0,0 +1,66 @@
+package locale
+
+import "testing"
+
+func TestPrinter_Plural(t *testing.T) {
+	tests := []struct {
+		Lang string
+		N    int
+		S    string
+	}{
+		{RU, 1, "1 минуту"},
+		{RU, 2, "2 минуты"},
+		{RU, 5, "5 минут"},
+		{RU, 11, "11 минут"},
+		{RU, 12, "12 минут"},
+		{RU, 21, "21 минуту"},
+		{RU, 24, "24 минуты"},
+		{RU, 111, "111 минут"},
+		{EN, 1, "1 minute"},
+		{EN, 2, "2 minutes"},
+		{EN, 0, "0 minutes"},
+	}
+	for _, tt := range tests {
+		if s := New(tt.Lang).Plural(MsgMinutes, tt.N); s != tt.S {
+			t.Errorf("%s %d: want %q, got %q", tt.Lang, tt.N, tt.S, s)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		Code string
+		Lang string
+	}{
+		{"ru", RU},
+		{"en-US", EN},
+		{"EN_gb", EN},
+		{"de", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if lang := Match(tt.Code); lang != tt.Lang {
+			t.Errorf("%q: want %q, got %q", tt.Code, tt.Lang, lang)
+		}
+	}
+}
+
+func TestNew_fallback(t *testing.T) {
+	if p := New("de"); p.Lang() != Default {
+		t.Errorf("want %q, got %q", Default, p.Lang())
+	}
+	if s := New(EN).Group("unknown"); s != `unknown group "unknown"` {
+		t.Errorf("unexpected group text %q", s)
+	}
+}
+
+// TestCatalog checks that every language has all messages of Default.
+func TestCatalog(t *testing.T) {
+	for lang, m := range catalog {
+		for key := range catalog[Default] {
+			if _, ok := m[key]; !ok {
+				t.Errorf("%s: no %q", lang, key)
+			}
+		}
+	}
+}
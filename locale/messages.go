@@ -0,0 +1,248 @@
+package locale
+
+// Message keys.
+const (
+	MsgHelp           = "help"
+	MsgUnknown        = "unknown"
+	MsgAmountUsage    = "amount_usage"
+	MsgMetalsUsage    = "metals_usage"
+	MsgRouteUsage     = "route_usage"
+	MsgExchangeFailed = "exchange_failed"
+
+	MsgSettingsUsage    = "settings_usage"
+	MsgPairsUsage       = "pairs_usage"
+	MsgGroupsUsage      = "groups_usage"
+	MsgPrecisionUsage   = "precision_usage"
+	MsgLanguageUsage    = "language_usage"
+	MsgSaveFailed       = "save_failed"
+	MsgPairs            = "pairs"
+	MsgGroups           = "groups"
+	MsgPrecision        = "precision"
+	MsgLanguage         = "language"
+	MsgLanguageAuto     = "language_auto"
+	MsgSubscribeUsage   = "subscribe_usage"
+	MsgUnsubscribeUsage = "unsubscribe_usage"
+
+	MsgSubscription         = "subscription"
+	MsgSubscriptions        = "subscriptions"
+	MsgNoSubscriptions      = "no_subscriptions"
+	MsgSubscriptionDeleted  = "subscription_deleted"
+	MsgSubscriptionNotFound = "subscription_not_found"
+	MsgSubscribeFailed      = "subscribe_failed"
+	MsgSubscribeCurrency    = "subscribe_currency"
+	MsgSubscribeGroup       = "subscribe_group"
+	MsgSubscribeLimit       = "subscribe_limit"
+	MsgUnsubscribeFailed    = "unsubscribe_failed"
+
+	MsgDonate         = "donate"
+	MsgDonateUsage    = "donate_usage"
+	MsgDonateCard     = "donate_card"
+	MsgDonateWishList = "donate_wish_list"
+	MsgDonateFooter   = "donate_footer"
+	MsgDonateOff      = "donate_off"
+	MsgDonateHidden   = "donate_hidden"
+	MsgDonateShown    = "donate_shown"
+
+	MsgBuy          = "buy"
+	MsgSell         = "sell"
+	MsgOp           = "op"
+	MsgOpSide       = "op_side"
+	MsgTarget       = "target"
+	MsgTargetGot    = "target_got"
+	MsgVia          = "via"
+	MsgTier         = "tier"
+	MsgTierSide     = "tier_side"
+	MsgMarkup       = "markup"
+	MsgGram         = "gram"
+	MsgActiveFrom   = "active_from"
+	MsgStale        = "stale"
+	MsgMinutes      = "minutes"
+	MsgHours        = "hours"
+	MsgSnapshotTime = "snapshot_time"
+	MsgTimeZone     = "time_zone"
+)
+
+// Prefixes of keys of currency group texts and short texts for buttons.
+const (
+	groupPrefix       = "group."
+	groupButtonPrefix = "group_button."
+)
+
+var catalog = map[string]map[string]string{
+	RU: {
+		MsgHelp: `Отправьте сумму, например *100* или *100 usd*, чтобы узнать, сколько она стоит по курсам ВТБ24.
+Чтобы узнать, сколько заплатить за нужную сумму, напишите, например, *нужно 1000 usd*.
+
+/rates - курсы за единицу валюты
+/metals - курсы драгоценных металлов за грамм
+/pairs - валютные пары для показа
+/groups - группы курсов и их порядок
+/precision - число знаков после запятой
+/language - язык ответов
+/settings - все настройки чата
+/route - самый выгодный способ обмена, например /route 1000 usd eur
+/subscribe - подписаться на изменение курса
+/subscriptions - список подписок
+/unsubscribe - удалить подписку
+/help - эта справка`,
+		MsgUnknown:        "Неизвестная команда. Список команд: /help",
+		MsgAmountUsage:    "Я понимаю только суммы, например: 100, 100 usd, 1.5k €, 5 000,50 евро в доллары, нужно 1000 usd.",
+		MsgMetalsUsage:    "Формат: /metals [граммы]",
+		MsgRouteUsage:     "Формат: /route 1000 usd eur",
+		MsgExchangeFailed: "Не удалось обменять %s.",
+
+		MsgSettingsUsage:    "Формат: `/settings` или `/settings reset`",
+		MsgPairsUsage:       "Формат: `/pairs USD/RUB EUR/USD ...` или `/pairs reset`",
+		MsgGroupsUsage:      "Формат: `/groups tele cash ...` или `/groups reset`. Группы:",
+		MsgPrecisionUsage:   "Формат: /precision от 0 до %d",
+		MsgLanguageUsage:    "Формат: /language %s или reset",
+		MsgSaveFailed:       "Не удалось сохранить настройки.",
+		MsgPairs:            "Валютные пары: %s",
+		MsgGroups:           "Группы: %s",
+		MsgPrecision:        "Знаков после запятой: %d",
+		MsgLanguage:         "Язык: %s",
+		MsgLanguageAuto:     "как в Telegram",
+		MsgSubscribeUsage:   "Формат: `/subscribe USD[/RUB] [группа] buy|sell <|<=|>|>= значение`",
+		MsgUnsubscribeUsage: "Формат: `/unsubscribe номер`",
+
+		MsgSubscription:         "Подписка %d: %s",
+		MsgSubscriptions:        "У вас %d подписка:|У вас %d подписки:|У вас %d подписок:",
+		MsgNoSubscriptions:      "Подписок нет.",
+		MsgSubscriptionDeleted:  "Подписка %d удалена.",
+		MsgSubscriptionNotFound: "Подписка %d не найдена.",
+		MsgSubscribeFailed:      "Не удалось сохранить подписку.",
+		MsgSubscribeCurrency:    "Неизвестная валютная пара. Пример: USD или EUR/USD.",
+		MsgSubscribeGroup:       "Неизвестная группа курсов. Группы можно посмотреть командой /groups.",
+		MsgSubscribeLimit:       "Подписок не может быть больше %d.",
+		MsgUnsubscribeFailed:    "Не удалось удалить подписку.",
+
+		MsgDonate:         "Спасибо, что хотите поддержать бота!",
+		MsgDonateUsage:    "Формат: /donate [on|off]",
+		MsgDonateCard:     "Карта: `%s`",
+		MsgDonateWishList: "[Список желаний](%s)",
+		MsgDonateFooter:   "_Нравится бот? Его можно поддержать:_",
+		MsgDonateOff:      "_Больше не показывать: /donate off_",
+		MsgDonateHidden:   "Больше не буду напоминать. Реквизиты всегда доступны по команде /donate.",
+		MsgDonateShown:    "Буду иногда напоминать о поддержке бота.",
+
+		MsgBuy:          "покупка",
+		MsgSell:         "продажа",
+		MsgOp:           "*%v* %v - *%v*%s (покупка) *%v*%s (продажа) %v",
+		MsgOpSide:       "*%v* %v - *%v*%s (%s) %v",
+		MsgTarget:       "Чтобы получить *%v* %v, нужно:",
+		MsgTargetGot:    "_(получите %v %v)_",
+		MsgVia:          "_через %s_",
+		MsgTier:         "от *%v* %v — курс %v (покупка) %v (продажа)",
+		MsgTierSide:     "от *%v* %v — курс %v (%s)",
+		MsgMarkup:       "наценка %s: покупка %s%%, продажа %s%%",
+		MsgGram:         "г",
+		MsgActiveFrom:   "_курс действует с %s_",
+		MsgStale:        "⚠️ _курсы не обновлялись %s и могут быть устаревшими_",
+		MsgMinutes:      "%d минуту|%d минуты|%d минут",
+		MsgHours:        "%d час|%d часа|%d часов",
+		MsgSnapshotTime: "_курсы на %s_",
+		MsgTimeZone:     "МСК",
+
+		groupPrefix + "tele":         "в ВТБ24 - онлайн",
+		groupPrefix + "cash":         "в офисе, наличные",
+		groupPrefix + "central-bank": "в офисе, безналичные",
+		groupPrefix + "cash-desk":    "в спецкассе",
+		groupPrefix + "metal":        "обезличенные металлические счета",
+		groupPrefix + "cbr":          "официальный курс ЦБ РФ",
+
+		groupButtonPrefix + "tele":         "онлайн",
+		groupButtonPrefix + "cash":         "наличные",
+		groupButtonPrefix + "central-bank": "безналичные",
+		groupButtonPrefix + "cash-desk":    "спецкасса",
+		groupButtonPrefix + "metal":        "ОМС",
+		groupButtonPrefix + "cbr":          "ЦБ РФ",
+	},
+	EN: {
+		MsgHelp: `Send an amount like *100* or *100 usd* to see how much it costs at VTB24 rates.
+To see how much to pay for an amount you need, send e.g. *need 1000 usd*.
+
+/rates - rates per currency unit
+/metals - precious metal rates per gram
+/pairs - currency pairs to show
+/groups - rate groups and their order
+/precision - number of decimals
+/language - language of replies
+/settings - all chat settings
+/route - the most profitable way to exchange, e.g. /route 1000 usd eur
+/subscribe - subscribe to rate changes
+/subscriptions - list subscriptions
+/unsubscribe - delete a subscription
+/help - this help`,
+		MsgUnknown:        "Unknown command. List of commands: /help",
+		MsgAmountUsage:    "I only understand amounts like 100, 100 usd, 1.5k €, 5 000.50 euro to dollars, need 1000 usd.",
+		MsgMetalsUsage:    "Usage: /metals [grams]",
+		MsgRouteUsage:     "Usage: /route 1000 usd eur",
+		MsgExchangeFailed: "Cannot exchange %s.",
+
+		MsgSettingsUsage:    "Usage: `/settings` or `/settings reset`",
+		MsgPairsUsage:       "Usage: `/pairs USD/RUB EUR/USD ...` or `/pairs reset`",
+		MsgGroupsUsage:      "Usage: `/groups tele cash ...` or `/groups reset`. Groups:",
+		MsgPrecisionUsage:   "Usage: /precision from 0 to %d",
+		MsgLanguageUsage:    "Usage: /language %s or reset",
+		MsgSaveFailed:       "Cannot save settings.",
+		MsgPairs:            "Currency pairs: %s",
+		MsgGroups:           "Groups: %s",
+		MsgPrecision:        "Decimals: %d",
+		MsgLanguage:         "Language: %s",
+		MsgLanguageAuto:     "as in Telegram",
+		MsgSubscribeUsage:   "Usage: `/subscribe USD[/RUB] [group] buy|sell <|<=|>|>= value`",
+		MsgUnsubscribeUsage: "Usage: `/unsubscribe number`",
+
+		MsgSubscription:         "Subscription %d: %s",
+		MsgSubscriptions:        "You have %d subscription:|You have %d subscriptions:",
+		MsgNoSubscriptions:      "No subscriptions.",
+		MsgSubscriptionDeleted:  "Subscription %d is deleted.",
+		MsgSubscriptionNotFound: "Subscription %d is not found.",
+		MsgSubscribeFailed:      "Cannot save the subscription.",
+		MsgSubscribeCurrency:    "Unknown currency pair. Example: USD or EUR/USD.",
+		MsgSubscribeGroup:       "Unknown group of rates. See groups with /groups.",
+		MsgSubscribeLimit:       "You cannot have more than %d subscriptions.",
+		MsgUnsubscribeFailed:    "Cannot delete the subscription.",
+
+		MsgDonate:         "Thank you for supporting the bot!",
+		MsgDonateUsage:    "Usage: /donate [on|off]",
+		MsgDonateCard:     "Card: `%s`",
+		MsgDonateWishList: "[Wish list](%s)",
+		MsgDonateFooter:   "_Like the bot? You can support it:_",
+		MsgDonateOff:      "_Do not show again: /donate off_",
+		MsgDonateHidden:   "I will not remind you again. Donation details are always available with /donate.",
+		MsgDonateShown:    "I will remind you about supporting the bot from time to time.",
+
+		MsgBuy:          "buy",
+		MsgSell:         "sell",
+		MsgOp:           "*%v* %v - *%v*%s (buy) *%v*%s (sell) %v",
+		MsgOpSide:       "*%v* %v - *%v*%s (%s) %v",
+		MsgTarget:       "To get *%v* %v, you need:",
+		MsgTargetGot:    "_(you get %v %v)_",
+		MsgVia:          "_via %s_",
+		MsgTier:         "from *%v* %v — rate %v (buy) %v (sell)",
+		MsgTierSide:     "from *%v* %v — rate %v (%s)",
+		MsgMarkup:       "markup %s: buy %s%%, sell %s%%",
+		MsgGram:         "g",
+		MsgActiveFrom:   "_rates are active from %s_",
+		MsgStale:        "⚠️ _rates were not updated for %s and may be outdated_",
+		MsgMinutes:      "%d minute|%d minutes",
+		MsgHours:        "%d hour|%d hours",
+		MsgSnapshotTime: "_rates as of %s_",
+		MsgTimeZone:     "MSK",
+
+		groupPrefix + "tele":         "VTB24 online",
+		groupPrefix + "cash":         "in office, cash",
+		groupPrefix + "central-bank": "in office, cashless",
+		groupPrefix + "cash-desk":    "at special cash desk",
+		groupPrefix + "metal":        "unallocated metal accounts",
+		groupPrefix + "cbr":          "official CBR rate",
+
+		groupButtonPrefix + "tele":         "online",
+		groupButtonPrefix + "cash":         "cash",
+		groupButtonPrefix + "central-bank": "cashless",
+		groupButtonPrefix + "cash-desk":    "cash desk",
+		groupButtonPrefix + "metal":        "metal accounts",
+		groupButtonPrefix + "cbr":          "CBR",
+	},
+}
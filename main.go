@@ -3,21 +3,30 @@ package main
 import (
 	"context"
 	"flag"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
+	"path/filepath"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/koorgoo/telegram"
+	"github.com/koorgoo/vtb24/alert"
 	"github.com/koorgoo/vtb24/api"
 	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/bot"
+	"github.com/koorgoo/vtb24/cbr"
 	"github.com/koorgoo/vtb24/chat"
 	"github.com/koorgoo/vtb24/config"
+	"github.com/koorgoo/vtb24/donate"
+	"github.com/koorgoo/vtb24/history"
+	"github.com/koorgoo/vtb24/locale"
+	"github.com/koorgoo/vtb24/metrics"
+	"github.com/koorgoo/vtb24/settings"
+	"github.com/koorgoo/vtb24/snapshot"
+	"github.com/koorgoo/vtb24/web"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -28,11 +37,16 @@ var OrderedGroups = []string{
 	api.GroupCashDesk,
 }
 
+// MetalGroups are groups to show metals in.
+var MetalGroups = append([]string{api.GroupMetal}, OrderedGroups...)
+
+// DefaultFilters select exchanges kept in memory. Currency pairs are
+// filtered per chat when replying.
 var DefaultFilters = []bank.ExFilter{
-	bank.WithGroup(OrderedGroups...),
-	bank.WithSrcDst(
-		api.USD, api.RUB,
-		api.EUR, api.RUB,
+	bank.AnyOf(
+		bank.WithGroup(OrderedGroups...),
+		bank.WithMetals(),
+		bank.WithProvider(cbr.ProviderName),
 	),
 }
 
@@ -49,89 +63,272 @@ func main() {
 	}
 
 	errc := make(chan error, 1)
-	termc := make(chan os.Signal)
+	termc := make(chan os.Signal, 1)
 	signal.Notify(termc, os.Interrupt, syscall.SIGTERM)
 
-	ex, err := GetDefaultEx()
+	if err = os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	hist, err := history.Open(filepath.Join(cfg.DataDir, "history.jsonl"), time.Duration(cfg.HistoryAge))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer hist.Close()
+
+	alerts, err := alert.Open(filepath.Join(cfg.DataDir, "alerts.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	alertc := make(chan alert.Alert, 100)
+
+	precision := chat.DefaultPrecision
+	prefs, err := settings.Open(filepath.Join(cfg.DataDir, "settings.json"), settings.Settings{
+		Pairs:     cfg.Pairs,
+		Groups:    OrderedGroups,
+		Precision: &precision,
+		MaxAge:    time.Duration(cfg.MaxRatesAge),
+	})
 	if err != nil {
-		errc <- err
+		log.Fatal(err)
+	}
+
+	var donations *donate.Store
+	if cfg.Donate != nil {
+		donations, err = donate.Open(filepath.Join(cfg.DataDir, "donate.json"), time.Duration(cfg.Donate.FooterInterval))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// The first provider is required, failures of the others are only
+	// logged.
+	vtb := &bank.VTB{Client: &api.Client{
+		Timeout: time.Duration(cfg.RequestTimeout),
+		Retries: *cfg.RequestRetries,
+	}}
+	official := &cbr.Provider{Client: &http.Client{
+		Timeout: time.Duration(cfg.RequestTimeout),
+	}}
+	providers := []bank.Provider{vtb, official}
+
+	// Cross pairs may be missing in upstream response and are computed
+	// through RUB, so require pairs to RUB and online rates only.
+	var required []string
+	for _, pair := range cfg.Pairs {
+		if _, dst, _ := api.ParsePair(pair); dst == api.RUB {
+			required = append(required, pair)
+		}
+	}
+
+	validator := &bank.Validator{
+		Provider: bank.ProviderVTB,
+		Pairs:    required,
+		Groups:   []string{api.GroupTele},
+		MaxJump:  cfg.MaxRateJump,
+	}
+
+	// Start from the last saved snapshot so the bot can reply while
+	// upstream is down. It is refreshed by the loop below, which retries
+	// when there are no rates at all.
+	snapPath := filepath.Join(cfg.DataDir, "snapshot.json")
+	snap, err := RestoreSnapshot(snapPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("failed to restore snapshot: %s", err)
+		}
+		if snap, err = GetDefaultEx(providers); err == nil {
+			err = validator.Validate(nil, snap.Ex)
+		}
+		if err != nil {
+			log.Printf("failed to fetch rates: %s", err)
+			snap = &bank.Snapshot{}
+		} else {
+			SaveSnapshot(snapPath, snap, vtb.Last(), official.Last())
+		}
 	}
 
 	var rates atomic.Value
-	rates.Store(ex)
+	rates.Store(snap)
+	loadSnapshot := func() *bank.Snapshot { return rates.Load().(*bank.Snapshot) }
+	loadRates := func() []bank.Ex { return loadSnapshot().Ex }
+
+	// Register metrics before the loop below updates them.
+	metrics.Register(loadSnapshot)
+	metrics.SetRates(snap)
 
 	go func() {
 		for {
 			t := time.Duration(cfg.RatesTimeout)
-			e, err := GetDefaultEx()
+			s, err := GetDefaultEx(providers)
 			if err == nil {
-				rates.Store(e)
+				err = validator.Validate(loadRates(), s.Ex)
+				if e, ok := err.(*bank.ValidationError); ok {
+					metrics.RejectedTotal.WithLabelValues(e.Reason).Inc()
+				}
+			}
+			if err == nil {
+				s.Ex = bank.WithPrevious(s.Ex, loadRates())
+				rates.Store(s)
+				SaveSnapshot(snapPath, s, vtb.Last(), official.Last())
+				metrics.RefreshTotal.WithLabelValues(metrics.ResultSuccess).Inc()
+				metrics.SetRates(s)
+				if err := hist.Append(s.Time, s.Ex); err != nil {
+					log.Printf("failed to save history: %s", err)
+				}
+				v, err := alerts.Check(s.Ex)
+				if err != nil {
+					log.Printf("failed to check alerts: %s", err)
+				}
+				for _, a := range v {
+					select {
+					case alertc <- a:
+					default:
+						log.Printf("alert dropped: %s", a.String())
+					}
+				}
 			} else {
 				log.Printf("failed to update rates: %s", err)
+				metrics.RefreshTotal.WithLabelValues(metrics.ResultFailure).Inc()
 				t = RatesRetryTimeout
 			}
 			time.Sleep(t)
 		}
 	}()
 
+	botAPI := &bot.API{
+		Token:  cfg.TelegramToken,
+		Client: &http.Client{Timeout: time.Duration(cfg.RequestTimeout)},
+	}
+	var webhook *bot.Webhook
+	if cfg.Webhook != nil {
+		webhook = bot.NewWebhook(cfg.Webhook.SecretToken)
+		http.Handle(cfg.Webhook.Path, webhook)
+	}
+
 	go func() {
 		http.Handle("/metrics", promhttp.Handler())
+		http.Handle("/v1/", web.NewHandler(loadSnapshot))
 		if err := http.ListenAndServe(cfg.WebAddr, nil); err != nil {
 			errc <- err
 		}
 	}()
 
 	go func() {
-		bot, err := telegram.NewBot(context.TODO(), cfg.TelegramToken)
-		if err != nil {
-			errc <- err
-			return
-		}
+		var updatec <-chan *telegram.Update
+		var sendMessage bot.SendFunc
 
-		go func(updatec <-chan *telegram.Update) {
-			for update := range updatec {
-				if update.Message == nil {
-					continue
+		// Long polling would conflict with the webhook, replies are sent
+		// with Bot API directly.
+		if webhook != nil {
+			if cfg.Webhook.URL != "" {
+				err := botAPI.SetWebhook(context.TODO(), cfg.Webhook.URL, cfg.Webhook.SecretToken)
+				if err != nil {
+					errc <- err
+					return
 				}
-				if update.Message.Text == nil {
-					continue
+			}
+			updatec, sendMessage = webhook.Updates(), botAPI.SendMessage
+		} else {
+			tg, err := telegram.NewBot(context.TODO(), cfg.TelegramToken)
+			if err != nil {
+				errc <- err
+				return
+			}
+			updatec = tg.Updates()
+			sendMessage = func(ctx context.Context, m *telegram.TextMessage) error {
+				_, err := tg.SendMessage(ctx, m)
+				return err
+			}
+
+			go func(errorc <-chan error) {
+				for err := range errorc {
+					log.Println(err)
 				}
+			}(tg.Errors())
+		}
 
-				n, err := strconv.ParseFloat(*update.Message.Text, 64)
-				if err != nil {
-					_, _ = bot.SendMessage(context.TODO(), &telegram.TextMessage{
-						ChatID: update.Message.Chat.ID,
-						Text:   "Я понимаю только числа.",
-					})
-					continue
+		send := func(ctx context.Context, m *telegram.TextMessage) error {
+			err := sendMessage(ctx, m)
+			if err != nil {
+				metrics.SendErrorsTotal.Inc()
+			}
+			return err
+		}
+		answer := func(ctx context.Context, id string, results []bot.InlineResult, cacheTime time.Duration) error {
+			err := botAPI.AnswerInlineQuery(ctx, id, results, cacheTime)
+			if err != nil {
+				metrics.SendErrorsTotal.Inc()
+			}
+			return err
+		}
+
+		keyboard := func(ctx context.Context, m *bot.KeyboardMessage) error {
+			err := botAPI.SendKeyboard(ctx, m)
+			if err != nil {
+				metrics.SendErrorsTotal.Inc()
+			}
+			return err
+		}
+
+		r := bot.NewRouter(send)
+		r.Handle("start", bot.Help)
+		r.Handle("help", bot.Help)
+		r.Handle("rates", bot.Rates(loadSnapshot, prefs))
+		r.Handle("metals", bot.Metals(loadSnapshot, MetalGroups, prefs))
+		r.Handle("pairs", bot.Pairs(prefs))
+		r.Handle("groups", bot.Groups(prefs, OrderedGroups))
+		r.Handle("precision", bot.Precision(prefs))
+		r.Handle("language", bot.Language(prefs))
+		r.Handle("settings", bot.Settings(prefs))
+		r.Handle("route", bot.Route(loadSnapshot, prefs))
+		r.Handle("subscribe", bot.Subscribe(alerts))
+		r.Handle("subscriptions", bot.Subscriptions(alerts))
+		r.Handle("unsubscribe", bot.Unsubscribe(alerts))
+		r.HandleText(bot.Amount(loadSnapshot, prefs))
+		r.HandleUnknown(bot.Unknown)
+		r.HandleInline(answer, bot.Inline(loadSnapshot, prefs, time.Duration(cfg.RatesTimeout)))
+		r.HandleCallback(keyboard, botAPI.AnswerCallbackQuery, bot.Browse(loadSnapshot, prefs))
+		r.Observe(metrics.ObserveMessage)
+		r.Language(bot.ChatLanguage(prefs))
+		if donations != nil {
+			details := donate.Details{
+				CardNumber:  cfg.Donate.CardNumber,
+				WishListURL: cfg.Donate.WishListURL,
+			}
+			footer := bot.DonateFooter(donations, details)
+			r.Handle("donate", bot.Donate(donations, details))
+			r.Footer(func(c *bot.Context) (string, error) {
+				s, err := footer(c)
+				if s != "" {
+					metrics.DonateFootersTotal.Inc()
 				}
+				return s, err
+			})
+		}
 
-				ex := rates.Load().([]bank.Ex)
-				text, mode := chat.MakeMessage(n, ex, OrderedGroups)
-				if text == "" {
-					_, _ = bot.SendMessage(context.TODO(), &telegram.TextMessage{
-						ChatID: update.Message.Chat.ID,
-						Text:   fmt.Sprintf("Не удалось обменять %v.", n),
-					})
-					continue
+		go func(updatec <-chan *telegram.Update) {
+			for update := range updatec {
+				if err := r.HandleUpdate(context.TODO(), update); err != nil {
+					log.Println(err)
 				}
+			}
+		}(updatec)
 
-				_, err = bot.SendMessage(context.TODO(), &telegram.TextMessage{
-					ChatID:    update.Message.Chat.ID,
-					Text:      text,
-					ParseMode: mode,
+		language := bot.ChatLanguage(prefs)
+		go func(alertc <-chan alert.Alert) {
+			for a := range alertc {
+				chatID := a.Subscription.ChatID
+				err := send(context.TODO(), &telegram.TextMessage{
+					ChatID:    chatID,
+					Text:      a.Format(locale.New(language(chatID, nil))),
+					ParseMode: telegram.ModeMarkdown,
 				})
 				if err != nil {
 					log.Println(err)
 				}
 			}
-		}(bot.Updates())
-
-		go func(errorc <-chan error) {
-			for err := range errorc {
-				log.Println(err)
-			}
-		}(bot.Errors())
+		}(alertc)
 	}()
 
 	select {
@@ -141,13 +338,49 @@ func main() {
 	}
 }
 
-func GetDefaultEx() ([]bank.Ex, error) {
-	c := new(api.Client)
-	resp, err := c.Request()
+func GetDefaultEx(providers []bank.Provider) (*bank.Snapshot, error) {
+	var ex []bank.Ex
+	for i, p := range providers {
+		v, err := p.Ex(context.TODO())
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			log.Printf("failed to fetch %s rates: %s", p.Name(), err)
+			continue
+		}
+		ex = append(ex, v...)
+	}
+	ex = bank.FilterEx(ex, DefaultFilters...)
+	return &bank.Snapshot{Ex: ex, Time: time.Now()}, nil
+}
+
+// RestoreSnapshot loads a snapshot saved by SaveSnapshot.
+func RestoreSnapshot(filename string) (*bank.Snapshot, error) {
+	saved, err := snapshot.Load(filename)
 	if err != nil {
 		return nil, err
 	}
-	ex := bank.ParseEx(resp)
+	ex := bank.ParseEx(saved.Response, saved.Time)
+	if saved.CBR != nil {
+		v, err := saved.CBR.Ex(saved.Time)
+		if err != nil {
+			log.Printf("failed to restore cbr rates: %s", err)
+		}
+		ex = append(ex, v...)
+	}
 	ex = bank.FilterEx(ex, DefaultFilters...)
-	return ex, nil
+	return &bank.Snapshot{Ex: ex, Time: saved.Time, Restored: true}, nil
+}
+
+// SaveSnapshot persists resp and official rates fetched for s. Failures
+// are only logged.
+func SaveSnapshot(filename string, s *bank.Snapshot, resp *api.Response, official *cbr.ValCurs) {
+	if resp == nil {
+		return
+	}
+	err := snapshot.Save(filename, &snapshot.Snapshot{Time: s.Time, Response: resp, CBR: official})
+	if err != nil {
+		log.Printf("failed to save snapshot: %s", err)
+	}
 }
@@ -0,0 +1,107 @@
+// Package metrics defines Prometheus metrics of the bot.
+package metrics
+
+import (
+	"time"
+
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "vtb24"
+
+var (
+	Rate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rate",
+		Help:      "Current base exchange rate.",
+	}, []string{"src", "dst", "group", "side"})
+
+	RefreshTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rates_refresh_total",
+		Help:      "Number of rates refreshes by result.",
+	}, []string{"result"})
+
+	RejectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rates_rejected_total",
+		Help:      "Number of snapshots rejected by validation by reason.",
+	}, []string{"reason"})
+
+	MessagesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_messages_total",
+		Help:      "Number of incoming Telegram messages by command.",
+	}, []string{"command"})
+
+	ReplyDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "telegram_reply_duration_seconds",
+		Help:      "Time spent handling a Telegram message.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"command"})
+
+	SendErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_send_errors_total",
+		Help:      "Number of failed SendMessage calls.",
+	})
+
+	DonateFootersTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "donate_footers_total",
+		Help:      "Number of donation footers shown in replies.",
+	})
+)
+
+// Refresh results.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Register registers all metrics. snapshot is used to report snapshot age.
+func Register(snapshot func() *bank.Snapshot) {
+	age := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "snapshot_age_seconds",
+		Help:      "Age of current rates snapshot.",
+	}, func() float64 {
+		s := snapshot()
+		if s == nil || s.Time.IsZero() {
+			return 0
+		}
+		return time.Since(s.Time).Seconds()
+	})
+
+	prometheus.MustRegister(
+		Rate,
+		RefreshTotal,
+		RejectedTotal,
+		MessagesTotal,
+		ReplyDuration,
+		SendErrorsTotal,
+		DonateFootersTotal,
+		age,
+	)
+}
+
+// SetRates replaces rate gauges with rates of s.
+func SetRates(s *bank.Snapshot) {
+	Rate.Reset()
+	for _, e := range s.Ex {
+		buy, sell, ok := bank.BaseRates(e)
+		if !ok {
+			continue
+		}
+		Rate.WithLabelValues(e.Src(), e.Dst(), e.Group(), "buy").Set(buy)
+		Rate.WithLabelValues(e.Src(), e.Dst(), e.Group(), "sell").Set(sell)
+	}
+}
+
+// ObserveMessage counts a handled message and its handling time.
+func ObserveMessage(command string, d time.Duration) {
+	MessagesTotal.WithLabelValues(command).Inc()
+	ReplyDuration.WithLabelValues(command).Observe(d.Seconds())
+}
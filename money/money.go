@@ -0,0 +1,187 @@
+// Package money implements exact decimal arithmetic for amounts and rates.
+package money
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var ErrSyntax = errors.New("money: invalid decimal")
+
+// Decimal is an exact rational number. Sums and products of decimals are
+// exact, quotients like 1/3 are exact too and are rounded only when
+// formatted. The zero value is 0.
+type Decimal struct {
+	r *big.Rat
+}
+
+// New returns unscaled * 10^-scale, e.g. New(5755, 2) is 57.55.
+func New(unscaled int64, scale int) Decimal {
+	r := new(big.Rat).SetInt64(unscaled)
+	return Decimal{r.Quo(r, new(big.Rat).SetInt(pow10(scale)))}
+}
+
+// Parse parses decimals like "57.55" or "-1". Fractions and exponents like
+// "1.5e3" are invalid, as big exponents take long to parse.
+func Parse(s string) (Decimal, error) {
+	if s == "" || strings.ContainsAny(s, "/eE") {
+		return Decimal{}, ErrSyntax
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Decimal{}, ErrSyntax
+	}
+	return Decimal{r}, nil
+}
+
+// FromFloat returns the shortest decimal which rounds to f, so values
+// parsed from decimal strings into float64 convert back exactly, e.g. 0.1
+// is 1/10 rather than 0.1000000000000000055511151231257827. NaN and
+// infinities are 0.
+func FromFloat(f float64) Decimal {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}
+	}
+	d, _ := Parse(strconv.FormatFloat(f, 'f', -1, 64))
+	return d
+}
+
+// Float returns the nearest float64 of d.
+func (d Decimal) Float() float64 {
+	f, _ := d.rat().Float64()
+	return f
+}
+
+func (d Decimal) rat() *big.Rat {
+	if d.r == nil {
+		return new(big.Rat)
+	}
+	return d.r
+}
+
+func (d Decimal) Add(e Decimal) Decimal { return Decimal{new(big.Rat).Add(d.rat(), e.rat())} }
+func (d Decimal) Sub(e Decimal) Decimal { return Decimal{new(big.Rat).Sub(d.rat(), e.rat())} }
+func (d Decimal) Mul(e Decimal) Decimal { return Decimal{new(big.Rat).Mul(d.rat(), e.rat())} }
+
+// Div returns d/e. It panics when e is 0.
+func (d Decimal) Div(e Decimal) Decimal { return Decimal{new(big.Rat).Quo(d.rat(), e.rat())} }
+
+// Cmp returns -1, 0 or +1 when d is less than, equal to or greater than e.
+func (d Decimal) Cmp(e Decimal) int { return d.rat().Cmp(e.rat()) }
+
+// Sign returns -1, 0 or +1 for negative, zero and positive d.
+func (d Decimal) Sign() int { return d.rat().Sign() }
+
+// IsInt reports whether d has no fractional part.
+func (d Decimal) IsInt() bool { return d.rat().IsInt() }
+
+// Rounding is a mode of rounding decimals.
+type Rounding int
+
+const (
+	// HalfEven rounds halves to the even neighbour, e.g. 0.125 to 0.12 and
+	// 0.135 to 0.14. It is known as bank rounding.
+	HalfEven Rounding = iota
+	// HalfUp rounds halves away from zero, e.g. 0.125 to 0.13.
+	HalfUp
+	// Floor rounds towards negative infinity.
+	Floor
+	// Ceil rounds towards positive infinity.
+	Ceil
+)
+
+// Round returns d rounded to places decimals with mode.
+func (d Decimal) Round(places int, mode Rounding) Decimal {
+	scale := pow10(places)
+	r := new(big.Rat).Mul(d.rat(), new(big.Rat).SetInt(scale))
+
+	// For r = q + m/den, q is the floor of r and 0 <= m < den.
+	q, m := new(big.Int).DivMod(r.Num(), r.Denom(), new(big.Int))
+	if m.Sign() != 0 {
+		var up bool
+		switch mode {
+		case Floor:
+		case Ceil:
+			up = true
+		default:
+			// Compare the fraction with a half.
+			switch c := new(big.Int).Lsh(m, 1).Cmp(r.Denom()); {
+			case c > 0:
+				up = true
+			case c == 0 && mode == HalfUp:
+				// Away from zero.
+				up = r.Sign() > 0
+			case c == 0:
+				up = q.Bit(0) == 1
+			}
+		}
+		if up {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Decimal{new(big.Rat).SetFrac(q, scale)}
+}
+
+// Text returns d with places decimals rounded with HalfEven.
+func (d Decimal) Text(places int) string {
+	return d.Round(places, HalfEven).rat().FloatString(places)
+}
+
+// String returns d exactly or, for fractions like 1/3, with 20 decimals.
+func (d Decimal) String() string {
+	r := d.rat()
+	for places := 0; places < 20; places++ {
+		if new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(places))).IsInt() {
+			return r.FloatString(places)
+		}
+	}
+	return d.Text(20)
+}
+
+// MarshalJSON encodes d as a JSON number.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON decodes d from a JSON number or a string with a number.
+// Null keeps d unchanged.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	v, err := Parse(strings.Trim(string(b), `"`))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// DefaultMinorUnits is a number of decimals of minor units of currencies
+// missing in MinorUnits, e.g. kopecks of RUB and cents of USD.
+const DefaultMinorUnits = 2
+
+// minorUnits are numbers of decimals of currencies without 2 decimals.
+var minorUnits = map[string]int{
+	"JPY": 0,
+}
+
+// MinorUnits returns a number of decimals of minor units of a currency.
+func MinorUnits(code string) int {
+	if n, ok := minorUnits[code]; ok {
+		return n
+	}
+	return DefaultMinorUnits
+}
+
+// RoundMinor rounds d to minor units of a currency with mode.
+func RoundMinor(d Decimal, code string, mode Rounding) Decimal {
+	return d.Round(MinorUnits(code), mode)
+}
@@ -0,0 +1,97 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+var RoundTests = []struct {
+	Value  string
+	Places int
+	Mode   Rounding
+	S      string
+}{
+	{"0.125", 2, HalfEven, "0.12"},
+	{"0.135", 2, HalfEven, "0.14"},
+	{"0.125", 2, HalfUp, "0.13"},
+	{"-0.125", 2, HalfUp, "-0.13"},
+	{"-0.125", 2, HalfEven, "-0.12"},
+	{"100.001", 2, HalfUp, "100.00"},
+	{"100.009", 2, Floor, "100.00"},
+	{"-100.001", 2, Floor, "-100.01"},
+	{"100.001", 2, Ceil, "100.01"},
+	{"100.5", 0, HalfEven, "100"},
+	{"101.5", 0, HalfEven, "102"},
+	{"1.005", 2, HalfUp, "1.01"},
+}
+
+func TestDecimal_Round(t *testing.T) {
+	for _, tt := range RoundTests {
+		d, err := Parse(tt.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := d.Round(tt.Places, tt.Mode).Text(tt.Places); s != tt.S {
+			t.Errorf("%s to %d places with mode %d: want %s, got %s", tt.Value, tt.Places, tt.Mode, tt.S, s)
+		}
+	}
+}
+
+func TestDecimal_exact(t *testing.T) {
+	// 0.1 + 0.2 is not 0.3 in float64.
+	if d := FromFloat(0.1).Add(FromFloat(0.2)); d.Cmp(New(3, 1)) != 0 {
+		t.Errorf("want 0.3, got %s", d)
+	}
+	// 1/59 * 59 is 1.
+	rate := New(59, 0)
+	if d := New(1, 0).Div(rate).Mul(rate); d.Cmp(New(1, 0)) != 0 || d.String() != "1" {
+		t.Errorf("want 1, got %s", d)
+	}
+	if s := New(1, 0).Div(New(3, 0)).String(); s != "0.33333333333333333333" {
+		t.Errorf("want 20 decimals of 1/3, got %s", s)
+	}
+	if s := New(5755, 2).String(); s != "57.55" {
+		t.Errorf("want 57.55, got %s", s)
+	}
+	if d := FromFloat(1e-7); d.Cmp(New(1, 7)) != 0 {
+		t.Errorf("want 0.0000001, got %s", d)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{"", "1/3", "abc", "1,5", "1.5e3", "1E1000000"} {
+		if _, err := Parse(s); err != ErrSyntax {
+			t.Errorf("%q: want ErrSyntax, got %v", s, err)
+		}
+	}
+}
+
+func TestRoundMinor(t *testing.T) {
+	d := New(188679, 2)
+	if s := RoundMinor(d, "JPY", HalfEven).String(); s != "1887" {
+		t.Errorf("JPY: want 1887, got %s", s)
+	}
+	if s := RoundMinor(d, "RUB", Floor).String(); s != "1886.79" {
+		t.Errorf("RUB: want 1886.79, got %s", s)
+	}
+}
+
+func TestDecimal_JSON(t *testing.T) {
+	var v struct{ A, B Decimal }
+	if err := json.Unmarshal([]byte(`{"A": 57.55, "B": "0.1"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"A":57.55,"B":0.1}`; string(b) != want {
+		t.Errorf("want %s, got %s", want, b)
+	}
+	if err := json.Unmarshal([]byte(`{"A": null}`), &v); err != nil || v.A.Cmp(New(5755, 2)) != 0 {
+		t.Errorf("want null ignored, got %v, %v", v.A, err)
+	}
+	if err := json.Unmarshal([]byte(`{"A": true}`), &v); err == nil {
+		t.Error("want error for a non-number")
+	}
+}
@@ -0,0 +1,77 @@
+// Package route finds the most profitable ways to exchange currencies.
+package route
+
+import (
+	"sort"
+
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/money"
+)
+
+// DefaultMaxSteps limits a number of exchanges in a route.
+const DefaultMaxSteps = 3
+
+// Route is a chain of exchanges. Each step exchanges the result of the
+// previous one.
+type Route struct {
+	Steps  []bank.Ex
+	Amount money.Decimal
+}
+
+// Find returns routes exchanging amount of src to dst ordered by resulting
+// amount, the best first. Exchanges are used in both directions. Routes
+// contain up to maxSteps exchanges and never visit a currency twice.
+func Find(v []bank.Ex, amount money.Decimal, src, dst string, maxSteps int) []Route {
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+
+	edges := map[string][]bank.Ex{}
+	for _, e := range v {
+		if _, ok := bank.Via(e); ok {
+			continue
+		}
+		edges[e.Src()] = append(edges[e.Src()], e)
+		i := bank.Invert(e)
+		edges[i.Src()] = append(edges[i.Src()], i)
+	}
+
+	f := &finder{edges: edges, dst: dst, maxSteps: maxSteps}
+	f.visit(src, amount, nil, map[string]bool{src: true})
+
+	sort.SliceStable(f.routes, func(i, j int) bool {
+		return f.routes[i].Amount.Cmp(f.routes[j].Amount) > 0
+	})
+	return f.routes
+}
+
+type finder struct {
+	edges    map[string][]bank.Ex
+	dst      string
+	maxSteps int
+	routes   []Route
+}
+
+func (f *finder) visit(cur string, amount money.Decimal, steps []bank.Ex, seen map[string]bool) {
+	if len(steps) == f.maxSteps {
+		return
+	}
+	for _, e := range f.edges[cur] {
+		if seen[e.Dst()] {
+			continue
+		}
+		// The bank buys cur from us and pays with e.Dst().
+		y, err := e.BuyDecimal(amount)
+		if err != nil {
+			continue
+		}
+		next := append(steps[:len(steps):len(steps)], e)
+		if e.Dst() == f.dst {
+			f.routes = append(f.routes, Route{Steps: next, Amount: y})
+			continue
+		}
+		seen[e.Dst()] = true
+		f.visit(e.Dst(), y, next, seen)
+		delete(seen, e.Dst())
+	}
+}
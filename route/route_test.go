@@ -0,0 +1,47 @@
+package route
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/money"
+)
+
+func TestFind(t *testing.T) {
+	ex := bank.ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("64"), Sell: api.MustValue("80")},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.EUR, Buy: api.MustValue("80"), Sell: api.MustValue("128")},
+			{CurrencyGroupAbbr: api.GroupCash, CurrencyAbbr: api.USD, Buy: api.MustValue("60"), Sell: api.MustValue("90")},
+			{CurrencyGroupAbbr: api.GroupCash, CurrencyAbbr: api.EUR, Buy: api.MustValue("70"), Sell: api.MustValue("100")},
+			{CurrencyGroupAbbr: api.GroupCash, CurrencyAbbr: "EUR/USD", Buy: api.MustValue("1"), Sell: api.MustValue("2")},
+		},
+	}, time.Time{})
+
+	routes := Find(ex, money.New(100, 0), api.USD, api.EUR, 0)
+
+	// Direct EUR/USD and four combinations through RUB.
+	if len(routes) != 5 {
+		t.Fatalf("want 5 routes, got %d", len(routes))
+	}
+	for i := 1; i < len(routes); i++ {
+		if routes[i-1].Amount.Cmp(routes[i].Amount) < 0 {
+			t.Errorf("routes are not ordered: %v < %v", routes[i-1].Amount, routes[i].Amount)
+		}
+	}
+
+	// 100 USD = 6400 RUB online = 64 EUR in the office.
+	best := routes[0]
+	if best.Amount.Cmp(money.New(64, 0)) != 0 {
+		t.Errorf("want 64, got %v", best.Amount)
+	}
+	if len(best.Steps) != 2 || best.Steps[0].Group() != api.GroupTele || best.Steps[1].Group() != api.GroupCash {
+		t.Errorf("want tele and cash steps, got %+v", best.Steps)
+	}
+
+	if routes := Find(ex, money.New(100, 0), api.USD, api.EUR, 1); len(routes) != 1 {
+		t.Errorf("want 1 direct route, got %d", len(routes))
+	}
+}
@@ -0,0 +1,125 @@
+// Package settings keeps per-chat preferences.
+package settings
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/koorgoo/vtb24/jsonfile"
+)
+
+// Settings are preferences of a chat. Empty fields fall back to defaults.
+type Settings struct {
+	// Pairs are currency pairs like "USD/RUB" to show. Empty pairs mean all
+	// pairs.
+	Pairs []string `json:"pairs,omitempty"`
+	// Groups are currency groups to show in order.
+	Groups []string `json:"groups,omitempty"`
+	// Precision is a number of decimals of amounts.
+	Precision *int `json:"precision,omitempty"`
+	// Language is a code of a language of replies like "ru".
+	Language string `json:"language,omitempty"`
+	// MaxAge is an age of rates after which replies warn that rates may be
+	// outdated. Zero disables the warning. It is not kept per chat.
+	MaxAge time.Duration `json:"-"`
+}
+
+func (s Settings) merge(d Settings) Settings {
+	if len(s.Pairs) == 0 {
+		s.Pairs = d.Pairs
+	}
+	if len(s.Groups) == 0 {
+		s.Groups = d.Groups
+	}
+	if s.Precision == nil {
+		s.Precision = d.Precision
+	}
+	if s.Language == "" {
+		s.Language = d.Language
+	}
+	if s.MaxAge == 0 {
+		s.MaxAge = d.MaxAge
+	}
+	return s
+}
+
+// clone returns a copy of s sharing no memory with it.
+func (s Settings) clone() Settings {
+	s.Pairs = append([]string(nil), s.Pairs...)
+	s.Groups = append([]string(nil), s.Groups...)
+	if s.Precision != nil {
+		p := *s.Precision
+		s.Precision = &p
+	}
+	return s
+}
+
+// Store keeps settings of chats in a JSON file.
+type Store struct {
+	filename string
+	defaults Settings
+
+	mu sync.Mutex
+	m  map[int64]Settings
+}
+
+// Open loads settings from filename, which may not exist yet.
+func Open(filename string, defaults Settings) (*Store, error) {
+	s := &Store{filename: filename, defaults: defaults, m: map[int64]Settings{}}
+	if err := jsonfile.Load(filename, &s.m); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("settings: %s", err)
+	}
+	return s, nil
+}
+
+// Defaults returns default settings.
+func (s *Store) Defaults() Settings { return s.defaults }
+
+// Get returns settings of a chat merged with defaults.
+func (s *Store) Get(chatID int64) Settings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m[chatID].merge(s.defaults)
+}
+
+// Update changes settings of a chat with f and saves them. Settings are
+// kept unchanged when saving fails.
+func (s *Store) Update(chatID int64, f func(*Settings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.copy()
+	v := m[chatID].clone()
+	f(&v)
+	m[chatID] = v
+	return s.save(m)
+}
+
+// Reset removes custom settings of a chat.
+func (s *Store) Reset(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.copy()
+	delete(m, chatID)
+	return s.save(m)
+}
+
+func (s *Store) copy() map[int64]Settings {
+	m := make(map[int64]Settings, len(s.m))
+	for k, v := range s.m {
+		m[k] = v
+	}
+	return m
+}
+
+// save writes m and makes it current.
+func (s *Store) save(m map[int64]Settings) error {
+	if err := jsonfile.Save(s.filename, m); err != nil {
+		return fmt.Errorf("settings: %s", err)
+	}
+	s.m = m
+	return nil
+}
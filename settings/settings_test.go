@@ -0,0 +1,76 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "settings.json")
+	precision := 2
+	defaults := Settings{Pairs: []string{"USD/RUB"}, Groups: []string{"tele"}, Precision: &precision, Language: "ru"}
+
+	s, err := Open(filename, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := s.Get(1); !reflect.DeepEqual(v, defaults) {
+		t.Errorf("want %+v, got %+v", defaults, v)
+	}
+
+	pairs := []string{"GBP/RUB", "EUR/USD"}
+	if err = s.Update(1, func(v *Settings) { v.Pairs = pairs }); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(filename, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := s.Get(1); !reflect.DeepEqual(v.Pairs, pairs) || !reflect.DeepEqual(v.Groups, defaults.Groups) {
+		t.Errorf("want %v with default groups, got %+v", pairs, v)
+	}
+	if v := s.Get(2); !reflect.DeepEqual(v, defaults) {
+		t.Errorf("want %+v, got %+v", defaults, v)
+	}
+
+	if err = s.Reset(1); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.Get(1); !reflect.DeepEqual(v, defaults) {
+		t.Errorf("want %+v, got %+v", defaults, v)
+	}
+}
+
+func TestStore_Update_failed(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(filepath.Join(dir, "settings.json"), Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Update(1, func(v *Settings) { v.Pairs = []string{"USD/RUB"} }); err != nil {
+		t.Fatal(err)
+	}
+	// Saving fails when the directory is gone.
+	if err = os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Update(1, func(v *Settings) { v.Pairs[0] = "EUR/RUB" }); err == nil {
+		t.Fatal("want error")
+	}
+	if err = s.Update(2, func(v *Settings) { v.Language = "en" }); err == nil {
+		t.Fatal("want error")
+	}
+	if err = s.Reset(1); err == nil {
+		t.Fatal("want error")
+	}
+	if v := s.Get(1); !reflect.DeepEqual(v.Pairs, []string{"USD/RUB"}) {
+		t.Errorf("want USD/RUB, got %+v", v)
+	}
+	if v := s.Get(2); v.Language != "" {
+		t.Errorf("want no language, got %+v", v)
+	}
+}
@@ -0,0 +1,44 @@
+// Package snapshot persists the last good upstream response.
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/cbr"
+	"github.com/koorgoo/vtb24/jsonfile"
+)
+
+// Snapshot is an upstream response and a time it was fetched at. CBR is
+// official rates fetched with the response, if any.
+type Snapshot struct {
+	Time     time.Time     `json:"time"`
+	Response *api.Response `json:"response"`
+	CBR      *cbr.ValCurs  `json:"cbr,omitempty"`
+}
+
+// Save writes s to filename atomically.
+func Save(filename string, s *Snapshot) error {
+	if err := jsonfile.Save(filename, s); err != nil {
+		return fmt.Errorf("snapshot: %s", err)
+	}
+	return nil
+}
+
+// Load reads a snapshot from filename. It returns an error satisfying
+// os.IsNotExist when no snapshot was saved.
+func Load(filename string) (*Snapshot, error) {
+	var s Snapshot
+	if err := jsonfile.Load(filename, &s); err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("snapshot: %s", err)
+	}
+	if s.Response == nil || len(s.Response.Items) == 0 {
+		return nil, fmt.Errorf("snapshot: %s: %s", filename, api.ErrEmptyItems)
+	}
+	return &s, nil
+}
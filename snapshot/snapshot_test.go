@@ -0,0 +1,47 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/cbr"
+)
+
+func TestSaveLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "snapshot.json")
+
+	if _, err := Load(filename); !os.IsNotExist(err) {
+		t.Fatalf("want not exist error, got %v", err)
+	}
+
+	s := &Snapshot{
+		Time: time.Date(2017, time.October, 1, 12, 0, 0, 0, time.UTC),
+		Response: &api.Response{
+			Items: []*api.Item{
+				{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("57.5"), Sell: api.MustValue("59.5")},
+			},
+		},
+		CBR: &cbr.ValCurs{Date: "13.10.2017", Valute: []cbr.Valute{{CharCode: api.USD, Nominal: 1, Value: "57,8383"}}},
+	}
+	if err := Save(filename, s); err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s2.Time.Equal(s.Time) {
+		t.Errorf("want %s, got %s", s.Time, s2.Time)
+	}
+	if a, b := s.Response.Items[0], s2.Response.Items[0]; a.Buy.Decimal().Cmp(b.Buy.Decimal()) != 0 || a.Sell.Decimal().Cmp(b.Sell.Decimal()) != 0 || a.CurrencyAbbr != b.CurrencyAbbr {
+		t.Errorf("want %+v, got %+v", a, b)
+	}
+	if s2.CBR == nil || !reflect.DeepEqual(*s.CBR, *s2.CBR) {
+		t.Errorf("want %+v, got %+v", s.CBR, s2.CBR)
+	}
+}
@@ -0,0 +1,208 @@
+// Package web serves exchange rates as JSON over HTTP.
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/money"
+)
+
+// SnapshotFunc returns current rates.
+type SnapshotFunc func() *bank.Snapshot
+
+// NewHandler returns a handler serving:
+//
+//	GET /v1/rates
+//	GET /v1/rates/{src}/{dst}
+//	GET /v1/convert?amount=&src=&dst=&group=
+func NewHandler(snapshot SnapshotFunc) http.Handler {
+	h := &handler{snapshot: snapshot}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/rates", h.rates)
+	mux.HandleFunc("/v1/rates/", h.pair)
+	mux.HandleFunc("/v1/convert", h.convert)
+	return mux
+}
+
+type handler struct {
+	snapshot SnapshotFunc
+}
+
+type ratesResponse struct {
+	Time  time.Time `json:"time"`
+	Rates []exJSON  `json:"rates"`
+}
+
+type exJSON struct {
+	Src   string     `json:"src"`
+	Dst   string     `json:"dst"`
+	Group string     `json:"group"`
+	Via   string     `json:"via,omitempty"`
+	Rates []rateJSON `json:"rates"`
+}
+
+// rateJSON has decimal strings, so clients do not lose precision of rates
+// parsing them as floats.
+type rateJSON struct {
+	Buy           string `json:"buy"`
+	Sell          string `json:"sell"`
+	BuyThreshold  string `json:"buy_threshold"`
+	SellThreshold string `json:"sell_threshold"`
+}
+
+func makeExJSON(v []bank.Ex) []exJSON {
+	a := make([]exJSON, len(v))
+	for i, e := range v {
+		a[i] = exJSON{Src: e.Src(), Dst: e.Dst(), Group: e.Group()}
+		a[i].Via, _ = bank.Via(e)
+		for _, r := range e.Rates() {
+			rr := rateJSON{Buy: r.BuyRate().String(), Sell: r.SellRate().String(), BuyThreshold: "0", SellThreshold: "0"}
+			if r.Threshold != nil {
+				rr.BuyThreshold = r.Threshold.BuyDecimal().String()
+				rr.SellThreshold = r.Threshold.SellDecimal().String()
+			}
+			a[i].Rates = append(a[i].Rates, rr)
+		}
+	}
+	return a
+}
+
+func (h *handler) rates(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	s := h.snapshot()
+	writeJSON(w, http.StatusOK, &ratesResponse{Time: s.Time, Rates: makeExJSON(s.Ex)})
+}
+
+func (h *handler) pair(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	a := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/rates/"), "/")
+	if len(a) != 2 {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	src, dst := strings.ToUpper(a[0]), strings.ToUpper(a[1])
+
+	s := h.snapshot()
+	v := findEx(s.Ex, src, dst)
+	if len(v) == 0 {
+		writeError(w, http.StatusNotFound, errNoRates)
+		return
+	}
+	writeJSON(w, http.StatusOK, &ratesResponse{Time: s.Time, Rates: makeExJSON(v)})
+}
+
+type convertResponse struct {
+	Time    time.Time       `json:"time"`
+	Amount  money.Decimal   `json:"amount"`
+	Src     string          `json:"src"`
+	Dst     string          `json:"dst"`
+	Results []convertResult `json:"results"`
+}
+
+type convertResult struct {
+	Group string `json:"group"`
+	Via   string `json:"via,omitempty"`
+	// Amount is an amount of dst the bank gives for the amount of src
+	// rounded to minor units of dst.
+	Amount money.Decimal `json:"amount"`
+}
+
+func (h *handler) convert(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	query := r.URL.Query()
+	amount, err := parseAmount(query.Get("amount"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errAmount)
+		return
+	}
+	src, dst := strings.ToUpper(query.Get("src")), strings.ToUpper(query.Get("dst"))
+	if src == "" || dst == "" {
+		writeError(w, http.StatusBadRequest, errCurrency)
+		return
+	}
+	group := query.Get("group")
+
+	s := h.snapshot()
+	resp := &convertResponse{Time: s.Time, Amount: amount, Src: src, Dst: dst}
+	// Official rates are not available for exchange.
+	ex := bank.FilterEx(s.Ex, bank.WithProvider(bank.ProviderVTB))
+	for _, e := range findEx(ex, src, dst) {
+		if group != "" && e.Group() != group {
+			continue
+		}
+		y, err := e.BuyDecimal(amount)
+		if err != nil {
+			continue
+		}
+		res := convertResult{Group: e.Group(), Amount: money.RoundMinor(y, dst, money.HalfEven)}
+		res.Via, _ = bank.Via(e)
+		resp.Results = append(resp.Results, res)
+	}
+	if len(resp.Results) == 0 {
+		writeError(w, http.StatusNotFound, errNoRates)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// MaxAmountLen limits the length of amounts to convert.
+const MaxAmountLen = 32
+
+// parseAmount parses a non-negative amount no longer than MaxAmountLen.
+func parseAmount(s string) (money.Decimal, error) {
+	if len(s) > MaxAmountLen {
+		return money.Decimal{}, errAmount
+	}
+	d, err := money.Parse(s)
+	if err != nil || d.Sign() < 0 {
+		return money.Decimal{}, errAmount
+	}
+	return d, nil
+}
+
+// findEx returns exchanges of src to dst quoted directly or through RUB.
+func findEx(v []bank.Ex, src, dst string) []bank.Ex {
+	a := bank.Find(v, src, dst)
+	if src != api.RUB && dst != api.RUB {
+		a = append(a, bank.CrossEx(v, src, dst, api.RUB)...)
+	}
+	return a
+}
+
+var (
+	errNotFound = errors.New("not found")
+	errNoRates  = errors.New("no rates")
+	errAmount   = errors.New("invalid amount")
+	errCurrency = errors.New("src and dst are required")
+	errMethod   = errors.New("method not allowed")
+)
+
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	writeError(w, http.StatusMethodNotAllowed, errMethod)
+	return false
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,165 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/koorgoo/vtb24/api"
+	"github.com/koorgoo/vtb24/bank"
+	"github.com/koorgoo/vtb24/exchange"
+	"github.com/koorgoo/vtb24/money"
+)
+
+var testTime = time.Date(2017, time.October, 1, 12, 0, 0, 0, time.UTC)
+
+func testSnapshot() *bank.Snapshot {
+	ex := bank.ParseEx(&api.Response{
+		Items: []*api.Item{
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.USD, Buy: api.MustValue("64"), Sell: api.MustValue("80")},
+			{CurrencyGroupAbbr: api.GroupTele, CurrencyAbbr: api.EUR, Buy: api.MustValue("80"), Sell: api.MustValue("128")},
+			{CurrencyGroupAbbr: api.GroupCash, CurrencyAbbr: api.USD, Buy: api.MustValue("60"), Sell: api.MustValue("90")},
+		},
+	}, time.Time{})
+	return &bank.Snapshot{Ex: ex, Time: testTime}
+}
+
+var HandlerTests = []struct {
+	Method string
+	URL    string
+	Code   int
+	Count  int // number of rates or results
+}{
+	{"GET", "/v1/rates", 200, 3},
+	{"POST", "/v1/rates", 405, 0},
+	{"GET", "/v1/rates/usd/rub", 200, 2},
+	{"GET", "/v1/rates/RUB/USD", 200, 2},
+	{"GET", "/v1/rates/USD/EUR", 200, 1},
+	{"GET", "/v1/rates/USD/GBP", 404, 0},
+	{"GET", "/v1/rates/USD", 404, 0},
+	{"GET", "/v1/convert?amount=100&src=USD&dst=RUB", 200, 2},
+	{"GET", "/v1/convert?amount=100&src=USD&dst=RUB&group=cash", 200, 1},
+	{"GET", "/v1/convert?amount=100&src=usd&dst=eur", 200, 1},
+	{"GET", "/v1/convert?amount=x&src=USD&dst=RUB", 400, 0},
+	{"GET", "/v1/convert?amount=1e1000000&src=USD&dst=RUB", 400, 0},
+	{"GET", "/v1/convert?amount=100000000000000000000000000000000&src=USD&dst=RUB", 400, 0},
+	{"GET", "/v1/convert?amount=-1&src=USD&dst=RUB", 400, 0},
+	{"GET", "/v1/convert?amount=100&src=USD", 400, 0},
+	{"GET", "/v1/convert?amount=100&src=USD&dst=GBP", 404, 0},
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(testSnapshot)
+
+	for _, tt := range HandlerTests {
+		t.Run(tt.Method+" "+tt.URL, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tt.Method, tt.URL, nil))
+
+			if w.Code != tt.Code {
+				t.Fatalf("want %d, got %d: %s", tt.Code, w.Code, w.Body)
+			}
+			if tt.Code != http.StatusOK {
+				return
+			}
+
+			var v struct {
+				Time    time.Time         `json:"time"`
+				Rates   []json.RawMessage `json:"rates"`
+				Results []json.RawMessage `json:"results"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			if !v.Time.Equal(testTime) {
+				t.Errorf("time: want %s, got %s", testTime, v.Time)
+			}
+			if n := len(v.Rates) + len(v.Results); n != tt.Count {
+				t.Errorf("want %d items, got %d", tt.Count, n)
+			}
+		})
+	}
+}
+
+func TestHandler_convert(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/v1/convert?amount=100&src=USD&dst=EUR", nil)
+	NewHandler(testSnapshot).ServeHTTP(w, r)
+
+	var v convertResponse
+	if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	// 100 USD = 6400 RUB = 50 EUR.
+	if len(v.Results) != 1 || v.Results[0].Amount.Cmp(money.New(50, 0)) != 0 || v.Results[0].Via != api.RUB {
+		t.Errorf("want 50 EUR via RUB, got %+v", v.Results)
+	}
+}
+
+func TestHandler_convert_official(t *testing.T) {
+	snapshot := func() *bank.Snapshot {
+		s := testSnapshot()
+		s.Ex = append(s.Ex, bank.NewEx(api.USD, api.RUB, api.GroupCBR, "cbr", time.Time{}, time.Time{},
+			exchange.New(exchange.Rate{Buy: 70, Sell: 70})))
+		return s
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/v1/convert?amount=1&src=USD&dst=RUB", nil)
+	NewHandler(snapshot).ServeHTTP(w, r)
+
+	var v convertResponse
+	if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range v.Results {
+		if res.Group == api.GroupCBR {
+			t.Errorf("want no official rates, got %+v", v.Results)
+		}
+	}
+	if len(v.Results) != 2 {
+		t.Errorf("want 2 bank results, got %+v", v.Results)
+	}
+}
+
+func TestHandler_convert_rounded(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/v1/convert?amount=1&src=RUB&dst=USD&group=tele", nil)
+	NewHandler(testSnapshot).ServeHTTP(w, r)
+
+	var v struct {
+		Results []struct {
+			Amount json.Number `json:"amount"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	// 1 RUB = 1/80 USD = 0.0125 USD.
+	if len(v.Results) != 1 || v.Results[0].Amount != "0.01" {
+		t.Errorf("want 0.01 USD, got %+v", v.Results)
+	}
+}
+
+func TestHandler_rates_decimal(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/v1/rates/RUB/USD", nil)
+	NewHandler(testSnapshot).ServeHTTP(w, r)
+
+	var v ratesResponse
+	if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range v.Rates {
+		if e.Group != api.GroupTele {
+			continue
+		}
+		// The bank buys RUB at 1/80 USD and sells at 1/64 USD.
+		if rr := e.Rates[0]; rr.Buy != "0.0125" || rr.Sell != "0.015625" {
+			t.Errorf("want 0.0125 and 0.015625, got %+v", rr)
+		}
+		return
+	}
+	t.Errorf("want a tele rate, got %+v", v.Rates)
+}